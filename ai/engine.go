@@ -0,0 +1,347 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Engine chooses moves for the player to move.
+// An Engine instance must not be used by multiple goroutines at the same time.
+type Engine interface {
+	// Search searches the board within the given limits and returns the best move together with statistics of the search.
+	// A cancelled search returns the result of the search so far together with ctx.Err().
+	Search(ctx context.Context, board *game.Board, limits Limits) (SearchResult, error)
+}
+
+// GameResetter is implemented by engines that keep knowledge of previous searches, e.g. in a transposition table.
+// NewGame discards this knowledge, so the engine can play an unrelated game like a newly created engine.
+type GameResetter interface {
+	NewGame()
+}
+
+// CloseEngine releases the resources of engines that implement io.Closer, e.g. the processes of external engines.
+// Other engines need no cleanup.
+func CloseEngine(engine Engine) error {
+	if closer, ok := engine.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// EngineFactory creates an engine from the options of an engine spec.
+// If the options are only validated, see EngineOptions.Validating, the factory may return a nil engine.
+type EngineFactory func(options *EngineOptions) (Engine, error)
+
+// EngineSpec selects an engine by name together with its options, written as name:key=value,key=value
+type EngineSpec struct {
+	Name    string
+	Options map[string]string
+}
+
+var engineFactories = map[string]EngineFactory{}
+
+func init() {
+	RegisterEngine("minimax", newMinimaxEngine)
+	RegisterEngine("mcts", newMCTSEngine)
+	RegisterEngine("random", newRandomEngine)
+}
+
+// RegisterEngine makes an engine available to NewEngine under the given name.
+// It panics if an engine with the same name is already registered.
+func RegisterEngine(name string, factory EngineFactory) {
+	if _, exists := engineFactories[name]; exists {
+		panic(fmt.Sprintf("engine %q is already registered", name))
+	}
+	engineFactories[name] = factory
+}
+
+// EngineNames returns the sorted names of all registered engines
+func EngineNames() []string {
+	names := make([]string, 0, len(engineFactories))
+	for name := range engineFactories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseEngineSpec parses an engine spec like "minimax:depth=7,symmetries=false"
+func ParseEngineSpec(spec string) (EngineSpec, error) {
+	name, options, _ := strings.Cut(spec, ":")
+	parsed := EngineSpec{Name: strings.TrimSpace(name), Options: make(map[string]string)}
+	if parsed.Name == "" {
+		return parsed, fmt.Errorf("engine spec %q has no engine name", spec)
+	}
+	if options == "" {
+		return parsed, nil
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		key, value, found := strings.Cut(option, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return parsed, fmt.Errorf("engine option %q must be written as key=value", option)
+		}
+		parsed.Options[key] = strings.TrimSpace(value)
+	}
+	return parsed, nil
+}
+
+// String returns the spec in the format read by ParseEngineSpec, with the options sorted by key
+func (s EngineSpec) String() string {
+	if len(s.Options) == 0 {
+		return s.Name
+	}
+	keys := make([]string, 0, len(s.Options))
+	for key := range s.Options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	options := make([]string, len(keys))
+	for i, key := range keys {
+		options[i] = key + "=" + s.Options[key]
+	}
+	return s.Name + ":" + strings.Join(options, ",")
+}
+
+// NewEngine creates the engine selected by the spec for games with the rules. The options depth and time are
+// available for all engines and override the corresponding default limits, all other options are passed to the engine.
+func NewEngine(spec EngineSpec, rules game.Rules, defaults Limits) (Engine, Limits, error) {
+	return newEngine(spec, rules, defaults, false)
+}
+
+// ValidateEngineSpec returns the error NewEngine would return for invalid options of the spec,
+// without creating the engine, e.g. without loading files or starting processes
+func ValidateEngineSpec(spec EngineSpec) error {
+	_, _, err := newEngine(spec, game.DefaultRules, Limits{}, true)
+	return err
+}
+
+func newEngine(spec EngineSpec, rules game.Rules, defaults Limits, validating bool) (Engine, Limits, error) {
+	factory, found := engineFactories[spec.Name]
+	if !found {
+		return nil, defaults, fmt.Errorf("unknown engine %q, available engines: %s", spec.Name, strings.Join(EngineNames(), ", "))
+	}
+
+	options := &EngineOptions{values: spec.Options, used: make(map[string]bool), rules: rules, validating: validating}
+	limits := Limits{
+		MaxDepth: options.Int("depth", defaults.MaxDepth),
+		MoveTime: options.Duration("time", defaults.MoveTime),
+	}
+	engine, err := factory(options)
+	if err != nil {
+		return nil, defaults, fmt.Errorf("engine %s: %w", spec.Name, err)
+	}
+	if err := options.Err(); err != nil {
+		return nil, defaults, fmt.Errorf("engine %s: %w", spec.Name, err)
+	}
+	return engine, limits, nil
+}
+
+// EngineOptions gives engine factories typed access to the options of an engine spec.
+// Invalid values and options that are never read are reported by Err.
+type EngineOptions struct {
+	values     map[string]string
+	used       map[string]bool
+	err        error
+	rules      game.Rules
+	validating bool
+}
+
+// Rules returns the rules of the games the engine is created for, e.g. to load files that depend on the rules
+func (o *EngineOptions) Rules() game.Rules {
+	return o.rules
+}
+
+// Validating reports whether the options are only validated by ValidateEngineSpec.
+// Factories then return after reading all options, before they load files or start processes.
+func (o *EngineOptions) Validating() bool {
+	return o.validating
+}
+
+func (o *EngineOptions) lookup(key string) (value string, found bool) {
+	o.used[key] = true
+	value, found = o.values[key]
+	return value, found
+}
+
+func (o *EngineOptions) fail(key, value string, err error) {
+	if o.err == nil {
+		o.err = fmt.Errorf("invalid value %q for option %s: %w", value, key, err)
+	}
+}
+
+// String returns the value of the option, or def if it is not set
+func (o *EngineOptions) String(key, def string) string {
+	if value, found := o.lookup(key); found {
+		return value
+	}
+	return def
+}
+
+// Int returns the value of the option, or def if it is not set
+func (o *EngineOptions) Int(key string, def int) int {
+	value, found := o.lookup(key)
+	if !found {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		o.fail(key, value, err)
+		return def
+	}
+	return parsed
+}
+
+// Float returns the value of the option, or def if it is not set
+func (o *EngineOptions) Float(key string, def float64) float64 {
+	value, found := o.lookup(key)
+	if !found {
+		return def
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		o.fail(key, value, err)
+		return def
+	}
+	return parsed
+}
+
+// Bool returns the value of the option, or def if it is not set
+func (o *EngineOptions) Bool(key string, def bool) bool {
+	value, found := o.lookup(key)
+	if !found {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		o.fail(key, value, err)
+		return def
+	}
+	return parsed
+}
+
+// Duration returns the value of the option, or def if it is not set
+func (o *EngineOptions) Duration(key string, def time.Duration) time.Duration {
+	value, found := o.lookup(key)
+	if !found {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		o.fail(key, value, err)
+		return def
+	}
+	return parsed
+}
+
+// Err returns the first invalid option value, or an error listing the options that no one read
+func (o *EngineOptions) Err() error {
+	if o.err != nil {
+		return o.err
+	}
+	var unknown []string
+	for key := range o.values {
+		if !o.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("unknown options: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// newMinimaxEngine supports the options algorithm (alphabeta or pvs), ordering (heuristic or history), evaluator,
+// symmetries, hash (memory of the transposition table in MB), threads, tablebase and book (file paths)
+func newMinimaxEngine(options *EngineOptions) (Engine, error) {
+	hash := options.Int("hash", DefaultTTSize)
+	if hash < 1 || hash > MaxTTSize {
+		return nil, fmt.Errorf("hash must be between 1 and %d MB, got %d", MaxTTSize, hash)
+	}
+	threads := options.Int("threads", 1)
+	if threads < 1 {
+		return nil, fmt.Errorf("threads must be at least 1, got %d", threads)
+	}
+	minimaxOptions := []Option{
+		WithSymmetries(options.Bool("symmetries", true)),
+		WithTranspositionTableSize(hash),
+		WithThreads(threads),
+	}
+	algorithm := options.String("algorithm", AlphaBeta.String())
+	if index := slices.Index(algorithmNames, algorithm); index >= 0 {
+		minimaxOptions = append(minimaxOptions, WithAlgorithm(Algorithm(index)))
+	} else {
+		return nil, fmt.Errorf("unknown algorithm %q, available algorithms: %s", algorithm, strings.Join(algorithmNames, ", "))
+	}
+	ordering := options.String("ordering", HeuristicOrdering.String())
+	if index := slices.Index(moveOrderingNames, ordering); index >= 0 {
+		minimaxOptions = append(minimaxOptions, WithMoveOrdering(MoveOrdering(index)))
+	} else {
+		return nil, fmt.Errorf("unknown move ordering %q, available orderings: %s", ordering, strings.Join(moveOrderingNames, ", "))
+	}
+	evaluator := options.String("evaluator", "lines")
+	if newEvaluator, found := evaluators[evaluator]; found {
+		minimaxOptions = append(minimaxOptions, WithEvaluator(newEvaluator()))
+	} else {
+		return nil, fmt.Errorf("unknown evaluator %q, available evaluators: %s", evaluator, strings.Join(EvaluatorNames(), ", "))
+	}
+	tablebasePath, bookPath := options.String("tablebase", ""), options.String("book", "")
+	if options.Validating() {
+		return nil, nil
+	}
+	if tablebasePath != "" {
+		tablebase, err := LoadTablebase(tablebasePath, options.Rules())
+		if err != nil {
+			return nil, err
+		}
+		minimaxOptions = append(minimaxOptions, WithTablebase(tablebase))
+	}
+	if bookPath != "" {
+		book, err := LoadOpeningBook(bookPath, options.Rules())
+		if err != nil {
+			return nil, err
+		}
+		minimaxOptions = append(minimaxOptions, WithOpeningBook(book))
+	}
+	return NewMinimax(minimaxOptions...), nil
+}
+
+// newMCTSEngine supports the options iterations, exploration, rollout (greedy or random) and seed
+func newMCTSEngine(options *EngineOptions) (Engine, error) {
+	mctsOptions := []MCTSOption{
+		WithIterations(options.Int("iterations", DefaultIterations)),
+		WithExploration(options.Float("exploration", DefaultExploration)),
+	}
+	switch rollout := options.String("rollout", "greedy"); rollout {
+	case "greedy":
+		mctsOptions = append(mctsOptions, WithRolloutPolicy(GreedyRollout))
+	case "random":
+		mctsOptions = append(mctsOptions, WithRolloutPolicy(RandomRollout))
+	default:
+		return nil, fmt.Errorf("unknown rollout policy %q", rollout)
+	}
+	if seed := options.Int("seed", 0); seed != 0 {
+		mctsOptions = append(mctsOptions, WithSeed(int64(seed)))
+	}
+	return NewMCTS(mctsOptions...), nil
+}
+
+// newRandomEngine supports the option seed
+func newRandomEngine(options *EngineOptions) (Engine, error) {
+	seed := int64(options.Int("seed", 0))
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return NewRandomEngine(rand.New(rand.NewSource(seed))), nil
+}
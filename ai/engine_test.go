@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEngineSpec(t *testing.T) {
+	spec, err := ParseEngineSpec("minimax:depth=7,symmetries=false")
+	assert.NoError(t, err)
+	assert.Equal(t, "minimax", spec.Name)
+	assert.Equal(t, map[string]string{"depth": "7", "symmetries": "false"}, spec.Options)
+	assert.Equal(t, "minimax:depth=7,symmetries=false", spec.String())
+
+	spec, err = ParseEngineSpec("random")
+	assert.NoError(t, err)
+	assert.Equal(t, "random", spec.String())
+
+	_, err = ParseEngineSpec("mcts:iterations")
+	assert.Error(t, err, "options must have a value")
+	_, err = ParseEngineSpec(":depth=3")
+	assert.Error(t, err, "spec must have a name")
+}
+
+func TestNewEngine(t *testing.T) {
+	defaults := Limits{MaxDepth: 9}
+	spec, _ := ParseEngineSpec("minimax:depth=7,time=2s")
+	engine, limits, err := NewEngine(spec, game.DefaultRules, defaults)
+	assert.NoError(t, err)
+	assert.Implements(t, (*Minimax)(nil), engine)
+	assert.Equal(t, Limits{MaxDepth: 7, MoveTime: 2 * time.Second}, limits)
+
+	spec, _ = ParseEngineSpec("mcts:iterations=100,rollout=random,seed=3")
+	_, limits, err = NewEngine(spec, game.DefaultRules, defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, limits, "limits must default to the given limits")
+
+	for _, invalid := range []string{"unknown", "minimax:depth=deep", "minimax:iterations=100", "minimax:threads=0", "minimax:algorithm=negascout", "minimax:ordering=random", "minimax:evaluator=smart", "mcts:rollout=smart"} {
+		spec, _ = ParseEngineSpec(invalid)
+		_, _, err = NewEngine(spec, game.DefaultRules, defaults)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidateEngineSpec(t *testing.T) {
+	spec, _ := ParseEngineSpec("minimax:evaluator=pieces,book=missing.txt")
+	assert.NoError(t, ValidateEngineSpec(spec), "files are not loaded when validating")
+	_, _, err := NewEngine(spec, game.DefaultRules, Limits{})
+	assert.Error(t, err)
+
+	for _, invalid := range []string{"unknown", "minimax:depth=deep", "minimax:hash=0", "minimax:hash=100000", "mcts:rollout=smart", "random:iterations=3"} {
+		spec, _ = ParseEngineSpec(invalid)
+		assert.Error(t, ValidateEngineSpec(spec), invalid)
+	}
+}
+
+func TestRandomEngine(t *testing.T) {
+	board := game.NewBoard()
+	spec, _ := ParseEngineSpec("random:seed=5")
+	engine, limits, err := NewEngine(spec, game.DefaultRules, Limits{})
+	assert.NoError(t, err)
+
+	for !board.Outcome().IsOver() {
+		result, err := engine.Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+		assert.NoError(t, board.MakeMove(result.BestMove), "random engine must play valid moves")
+	}
+}
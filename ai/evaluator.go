@@ -1,31 +1,98 @@
 package ai
 
-import "gibhub.com/bef1993/gobblet-gobblers/game"
+import (
+	"slices"
 
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Evaluations are given from the perspective of Player 1. Heuristic evaluations lie strictly between Player2Win and
+// Player1Win. Wins whose distance to the end of the game is known are evaluated by WinScore beyond these bounds,
+// a proven win of unknown distance is evaluated as exactly Player1Win or Player2Win.
 const (
 	Player1Win int = 1000
 	Player2Win int = -1000
 	NoWin      int = 0
+
+	// MaxWinPlies is the largest distance to the end of the game that win scores can express
+	MaxWinPlies = 1 << 14
 )
 
 type Evaluator interface {
-	Evaluate(board *game.Board, depthRemaining int) (evaluation int)
+	// Evaluate evaluates the board, which is ply moves away from the root of the search.
+	// Ended games are evaluated by WinScore, so faster wins get better evaluations.
+	Evaluate(board *game.Board, ply int) (evaluation int)
 	EvaluateMove(board *game.Board, move game.Move) (evaluation int)
 }
 
+// WinScore returns the evaluation of a win of the player after the given number of plies
+func WinScore(winner game.Player, plies int) int {
+	plies = min(max(plies, 0), MaxWinPlies-1)
+	if winner == game.Player1 {
+		return Player1Win + MaxWinPlies - plies
+	}
+	return Player2Win - MaxWinPlies + plies
+}
+
+// ForcedWin returns the winner of an evaluation that proves a win together with the number of plies until the end
+// of the game, which is -1 if the distance is unknown. The winner is game.None for all other evaluations.
+func ForcedWin(evaluation int) (winner game.Player, plies int) {
+	switch {
+	case evaluation > Player1Win:
+		return game.Player1, Player1Win + MaxWinPlies - evaluation
+	case evaluation == Player1Win:
+		return game.Player1, -1
+	case evaluation < Player2Win:
+		return game.Player2, evaluation - Player2Win + MaxWinPlies
+	case evaluation == Player2Win:
+		return game.Player2, -1
+	default:
+		return game.None, 0
+	}
+}
+
+// WinIn returns the signed number of plies until the end of the game proven by an evaluation, see SearchResult.WinIn
+func WinIn(evaluation int) int {
+	switch winner, plies := ForcedWin(evaluation); {
+	case plies < 0:
+		return 0
+	case winner == game.Player1:
+		return plies
+	default:
+		return -plies
+	}
+}
+
+// evaluators maps the names accepted by the evaluator option of the minimax engine to their constructors
+var evaluators = map[string]func() Evaluator{
+	"lines":  NewEvaluator,
+	"pieces": NewPiecesEvaluator,
+}
+
+// EvaluatorNames returns the sorted names of the evaluators of the minimax engine
+func EvaluatorNames() []string {
+	names := make([]string, 0, len(evaluators))
+	for name := range evaluators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
 type evaluator struct{}
 
+// NewEvaluator creates the default evaluator, which rates the lines that only contain pieces of one player
 func NewEvaluator() Evaluator {
 	return &evaluator{}
 }
 
-func (e *evaluator) Evaluate(b *game.Board, depthRemaining int) int {
-	switch b.CheckWin() {
-	case game.Player1:
-		return Player1Win + depthRemaining // prefer faster wins
-	case game.Player2:
-		return Player2Win - depthRemaining // prefer delaying losses
-	case game.None:
+func (e *evaluator) Evaluate(b *game.Board, ply int) int {
+	switch outcome := b.Outcome(); outcome {
+	case game.Player1Wins, game.Player2Wins:
+		return WinScore(outcome.Winner(), ply) // prefer faster wins and delaying losses
+	case game.Drawn:
+		return NoWin
+	case game.Undecided:
 		// Heuristic evaluation for non-terminal positions
 		return e.calculateHeuristicScore(b)
 	default:
@@ -41,10 +108,10 @@ func (e *evaluator) EvaluateMove(b *game.Board, move game.Move) int {
 }
 
 func (e *evaluator) calculateHeuristicScore(b *game.Board) int {
-	if b.CheckWin() == game.Player1 {
+	switch b.CheckWin() {
+	case game.Player1:
 		return Player1Win
-	}
-	if b.CheckWin() == game.Player2 {
+	case game.Player2:
 		return Player2Win
 	}
 
@@ -53,7 +120,8 @@ func (e *evaluator) calculateHeuristicScore(b *game.Board) int {
 		score += evaluateLine(line)
 	}
 
-	return score
+	// Larger boards have many overlapping lines, a heuristic score must never look like a win
+	return max(min(score, Player1Win-1), Player2Win+1)
 }
 
 func evaluateLine(line game.Line) int {
@@ -76,20 +144,66 @@ func evaluateLine(line game.Line) int {
 	}
 
 	// Player 1 has potential
-	if p1PieceCount == 2 {
-		return 100
-	}
-	if p1PieceCount == 1 {
-		return 10
+	if p1PieceCount > 0 {
+		return linePotential(p1PieceCount, len(line))
 	}
 
 	// Player 2 has potential
-	if p2PieceCount == 2 {
-		return -100
-	}
-	if p2PieceCount == 1 {
-		return -10
+	if p2PieceCount > 0 {
+		return -linePotential(p2PieceCount, len(line))
 	}
 
 	return 0 // Empty line
 }
+
+// linePotential rates a line that contains only pieces of one player, lines missing a single piece are rated highest
+func linePotential(pieceCount, lineLength int) int {
+	if pieceCount == lineLength-1 {
+		return 100
+	}
+	return 10 * pieceCount
+}
+
+// piecesEvaluator rates the visible pieces on the board by their size, as larger pieces can gobble more pieces
+// and can be gobbled by fewer. It knows nothing about lines, which makes it a weaker baseline for the line heuristic.
+type piecesEvaluator struct{}
+
+// NewPiecesEvaluator creates an evaluator that rates the sizes of the visible pieces of both players
+func NewPiecesEvaluator() Evaluator {
+	return &piecesEvaluator{}
+}
+
+func (e *piecesEvaluator) Evaluate(b *game.Board, ply int) int {
+	switch outcome := b.Outcome(); outcome {
+	case game.Player1Wins, game.Player2Wins:
+		return WinScore(outcome.Winner(), ply)
+	case game.Drawn:
+		return NoWin
+	default:
+		return piecesScore(b)
+	}
+}
+
+func (e *piecesEvaluator) EvaluateMove(b *game.Board, move game.Move) int {
+	b.MustMakeMove(move)
+	defer b.MustUndoMove(move)
+	switch b.CheckLineWin() {
+	case game.Player1:
+		return Player1Win
+	case game.Player2:
+		return Player2Win
+	}
+	return piecesScore(b)
+}
+
+func piecesScore(b *game.Board) int {
+	score := 0
+	for r := range b.Grid {
+		for c := range b.Grid[r] {
+			if top := b.Grid[r][c].TopPiece(); top != nil {
+				score += 10 * (int(top.Size) + 1) * playerSign(top.Owner)
+			}
+		}
+	}
+	return max(min(score, Player1Win-1), Player2Win+1)
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Iteration is the result of a completed iteration of an iterative deepening search
+type Iteration struct {
+	Depth      int
+	Evaluation int
+	BestMove   game.Move
+	PV         []game.Move // Principal variation of the iteration, starting with BestMove
+	Nodes      int         // Nodes visited by the search so far
+	Elapsed    time.Duration
+}
+
+// GetBestMoveTimed searches with increasing depth until moveTime is used up or maxDepth is reached.
+// It returns the best move of the deepest completed iteration together with the results of all completed iterations.
+// The best moves of previous iterations are stored in the transposition table and searched first in the next iteration.
+func (m *minimax) GetBestMoveTimed(board *game.Board, moveTime time.Duration, maxDepth int) (bestMove game.Move, iterations []Iteration) {
+	result, _ := m.Search(context.Background(), board, Limits{MaxDepth: maxDepth, MoveTime: moveTime})
+	return result.BestMove, result.Iterations
+}
+
+// iterativeDeepening searches with increasing depth until ctx is done or maxDepth is reached, and reports every
+// completed iteration to onIteration, which may be nil
+func (m *minimax) iterativeDeepening(ctx context.Context, board *game.Board, maxDepth int, onIteration func(Iteration)) (result SearchResult) {
+	if result, found := m.lookupKnownResult(board); found {
+		return result
+	}
+
+	// Fall back to the heuristically best move if not even the first iteration completes
+	result.BestMove = m.fallbackMove(board)
+	if result.BestMove == (game.Move{}) {
+		return result
+	}
+
+	defer m.startSearch(ctx)()
+	start := time.Now()
+	stopHelpers := m.startHelpers(board, maxDepth)
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		evaluation, move := m.aspirationSearch(board, depth, result.Evaluation)
+		if m.aborted {
+			break
+		}
+
+		result.BestMove = move
+		result.Evaluation = evaluation
+		result.PV = append([]game.Move(nil), m.pv[0]...)
+		result.Depth = depth
+		iteration := Iteration{Depth: depth, Evaluation: evaluation, BestMove: move, PV: result.PV, Nodes: m.stats.Nodes,
+			Elapsed: time.Since(start)}
+		result.Iterations = append(result.Iterations, iteration)
+		if onIteration != nil {
+			onIteration(iteration)
+		}
+
+		// A deeper search can not change the outcome of a decided game
+		if evaluation >= Player1Win || evaluation <= Player2Win {
+			break
+		}
+	}
+
+	m.stats.add(stopHelpers())
+	result.Elapsed = time.Since(start)
+	result.SearchStats = m.stats
+	return result
+}
@@ -0,0 +1,351 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+const (
+	DefaultIterations  = 20000
+	DefaultExploration = math.Sqrt2
+
+	// maxRolloutPlies ends rollouts that do not finish, e.g. because pieces are moved back and forth
+	maxRolloutPlies = 100
+	// mctsEvaluationScale maps the win rate of a search to an evaluation between -mctsEvaluationScale and +mctsEvaluationScale
+	mctsEvaluationScale = 100
+)
+
+// RolloutPolicy chooses the moves of a rollout from the possible moves of the board
+type RolloutPolicy func(board *game.Board, moves []game.Move, random *rand.Rand) game.Move
+
+// RandomRollout chooses a random move
+func RandomRollout(_ *game.Board, moves []game.Move, random *rand.Rand) game.Move {
+	return moves[random.Intn(len(moves))]
+}
+
+// GreedyRollout chooses a move that wins immediately if there is one, otherwise a random move
+func GreedyRollout(board *game.Board, moves []game.Move, random *rand.Rand) game.Move {
+	if winningMove, found := findWinningMove(board, moves); found {
+		return winningMove
+	}
+	return RandomRollout(board, moves, random)
+}
+
+// mcts is a Monte Carlo tree search using UCT for selection and random rollouts for evaluation.
+// Proven wins and losses are propagated through the tree (MCTS-Solver), so forced wins can be detected.
+type mcts struct {
+	iterations  int
+	exploration float64
+	rollout     RolloutPolicy
+	random      *rand.Rand
+}
+
+// mctsNode is a position in the search tree, reached by move from its parent
+type mctsNode struct {
+	move     game.Move
+	parent   *mctsNode
+	children []*mctsNode
+	untried  []game.Move
+	visits   int
+	wins     float64 // Sum of the rollout results from the perspective of the player who made the move
+	proven   Outcome // Proven outcome for the player to move, OutcomeDraw if unknown
+	isProven bool
+}
+
+// MCTSOption configures an MCTS instance
+type MCTSOption func(m *mcts)
+
+// WithIterations sets the maximum number of iterations per search
+func WithIterations(iterations int) MCTSOption {
+	return func(m *mcts) {
+		m.iterations = iterations
+	}
+}
+
+// WithExploration sets the exploration constant of UCT
+func WithExploration(exploration float64) MCTSOption {
+	return func(m *mcts) {
+		m.exploration = exploration
+	}
+}
+
+// WithRolloutPolicy sets the policy that chooses the moves of rollouts
+func WithRolloutPolicy(rollout RolloutPolicy) MCTSOption {
+	return func(m *mcts) {
+		m.rollout = rollout
+	}
+}
+
+// WithSeed makes the search deterministic
+func WithSeed(seed int64) MCTSOption {
+	return func(m *mcts) {
+		m.random = rand.New(rand.NewSource(seed))
+	}
+}
+
+// NewMCTS creates a Monte Carlo tree search with the Minimax interface.
+// The depth of the limits is ignored, a search runs the configured number of iterations
+// or as many iterations as fit into the move time.
+func NewMCTS(options ...MCTSOption) Minimax {
+	m := &mcts{
+		iterations:  DefaultIterations,
+		exploration: DefaultExploration,
+		rollout:     GreedyRollout,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+func (m *mcts) Search(ctx context.Context, board *game.Board, limits Limits) (SearchResult, error) {
+	start := time.Now()
+	var deadline time.Time
+	if limits.MoveTime > 0 {
+		deadline = start.Add(limits.MoveTime)
+	}
+
+	root := m.newNode(board, game.Move{}, nil)
+	result := SearchResult{}
+	var err error
+
+	for iteration := 0; iteration < m.iterations && !root.isProven; iteration++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		depth := m.iterate(board, root)
+		result.Nodes++
+		result.Depth = max(result.Depth, depth)
+	}
+
+	result.PV = principalVariation(root)
+	if len(result.PV) > 0 {
+		result.BestMove = result.PV[0]
+	}
+	result.Evaluation = m.evaluation(board, root)
+	result.Elapsed = time.Since(start)
+	return result, err
+}
+
+// iterate runs one selection, expansion, rollout and backpropagation and returns the depth of the expanded node
+func (m *mcts) iterate(board *game.Board, root *mctsNode) (depth int) {
+	var path []game.Move
+	node := root
+
+	// Selection
+	for len(node.untried) == 0 && len(node.children) > 0 && !node.isProven {
+		node = m.selectChild(node)
+		board.MustMakeMove(node.move)
+		path = append(path, node.move)
+	}
+
+	// Expansion
+	if len(node.untried) > 0 && !node.isProven {
+		i := m.random.Intn(len(node.untried))
+		move := node.untried[i]
+		node.untried[i] = node.untried[len(node.untried)-1]
+		node.untried = node.untried[:len(node.untried)-1]
+
+		board.MustMakeMove(move)
+		path = append(path, move)
+		child := m.newNode(board, move, node)
+		node.children = append(node.children, child)
+		node = child
+	}
+
+	// Rollout from the perspective of the player who made the move into the node
+	var result float64
+	if node.isProven {
+		result = 1
+		if node.proven == OutcomeWin {
+			result = 0
+		}
+		updateProof(node)
+	} else {
+		result = m.simulate(board)
+	}
+
+	// Backpropagation
+	for ; node != nil; node = node.parent {
+		node.visits++
+		node.wins += result
+		result = 1 - result
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		board.MustUndoMove(path[i])
+	}
+	return len(path)
+}
+
+func (m *mcts) newNode(board *game.Board, move game.Move, parent *mctsNode) *mctsNode {
+	node := &mctsNode{move: move, parent: parent}
+	switch outcome := board.Outcome(); outcome {
+	case game.Undecided:
+		node.untried = board.GetPossibleMoves()
+	case game.Drawn:
+		// Drawn positions are leaves whose rollouts always end in a draw
+	default:
+		node.isProven = true
+		node.proven = OutcomeLoss
+		if outcome.Winner() == board.ActivePlayer {
+			node.proven = OutcomeWin
+		}
+	}
+	return node
+}
+
+// selectChild chooses the child with the highest UCT value. Children that are proven losses for the opponent are chosen
+// immediately, children that are proven wins for the opponent are only chosen if there is no other child.
+func (m *mcts) selectChild(node *mctsNode) *mctsNode {
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	logVisits := math.Log(float64(node.visits))
+
+	for _, child := range node.children {
+		value := child.wins/float64(child.visits) + m.exploration*math.Sqrt(logVisits/float64(child.visits))
+		if child.isProven {
+			if child.proven == OutcomeLoss {
+				return child
+			}
+			value = math.Inf(-1)
+		}
+		if best == nil || value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// updateProof propagates the proven outcome of a node to its ancestors
+func updateProof(node *mctsNode) {
+	for parent := node.parent; parent != nil && !parent.isProven; node, parent = parent, parent.parent {
+		if node.proven == OutcomeLoss {
+			// The player to move in the parent can move into a lost position for the opponent
+			parent.isProven, parent.proven = true, OutcomeWin
+			continue
+		}
+
+		if len(parent.untried) > 0 {
+			return
+		}
+		for _, sibling := range parent.children {
+			if !sibling.isProven || sibling.proven != OutcomeWin {
+				return
+			}
+		}
+		// All moves of the parent lead to positions won by the opponent
+		parent.isProven, parent.proven = true, OutcomeLoss
+	}
+}
+
+// simulate plays moves chosen by the rollout policy until the game ends and
+// returns 1 if the player who made the last move before the rollout wins, 0 if they lose and 0.5 for draws and unfinished rollouts
+func (m *mcts) simulate(board *game.Board) float64 {
+	player := board.ActivePlayer.Opponent()
+	var moves []game.Move
+	result := 0.5
+
+	for ply := 0; ply < maxRolloutPlies; ply++ {
+		if outcome := board.Outcome(); outcome == game.WinOf(player) {
+			result = 1
+			break
+		} else if outcome == game.WinOf(player.Opponent()) {
+			result = 0
+			break
+		} else if outcome == game.Drawn {
+			break
+		}
+		move := m.rollout(board, board.GetPossibleMoves(), m.random)
+		board.MustMakeMove(move)
+		moves = append(moves, move)
+	}
+
+	for i := len(moves) - 1; i >= 0; i-- {
+		board.MustUndoMove(moves[i])
+	}
+	return result
+}
+
+// evaluation converts the proven outcome or the win rate of the root into an evaluation from the perspective of Player 1
+func (m *mcts) evaluation(board *game.Board, root *mctsNode) int {
+	sign := 1
+	if !isMaximizingPlayer(board.ActivePlayer) {
+		sign = -1
+	}
+
+	if root.isProven {
+		if root.proven == OutcomeWin {
+			return sign * Player1Win
+		}
+		return -sign * Player1Win
+	}
+	if root.visits == 0 {
+		return NoWin
+	}
+	// The wins of the root are counted from the perspective of the opponent of the player to move
+	winRate := 1 - root.wins/float64(root.visits)
+	return sign * int(math.Round((2*winRate-1)*mctsEvaluationScale))
+}
+
+// principalVariation follows the most visited children, or the winning children of proven wins, from the root
+func principalVariation(root *mctsNode) []game.Move {
+	var pv []game.Move
+	for node := root; len(node.children) > 0; {
+		var best *mctsNode
+		for _, child := range node.children {
+			if child.isProven && child.proven == OutcomeLoss {
+				best = child
+				break
+			}
+			if best == nil || child.visits > best.visits {
+				best = child
+			}
+		}
+		pv = append(pv, best.move)
+		node = best
+	}
+	return pv
+}
+
+func (m *mcts) CalculateWinner(board *game.Board, maxDepth int) (winner game.Player) {
+	winner, _ = m.CalculateWinnerContext(context.Background(), board, maxDepth)
+	return winner
+}
+
+// CalculateWinnerContext returns the winner if the search could prove a forced win, game.None otherwise
+func (m *mcts) CalculateWinnerContext(ctx context.Context, board *game.Board, maxDepth int) (winner game.Player, err error) {
+	result, err := m.Search(ctx, board, Limits{MaxDepth: maxDepth})
+	if err != nil {
+		return game.None, err
+	}
+	if result.Evaluation >= Player1Win {
+		return game.Player1, nil
+	} else if result.Evaluation <= Player2Win {
+		return game.Player2, nil
+	}
+	return game.None, nil
+}
+
+func (m *mcts) GetBestMove(board *game.Board, maxDepth int) game.Move {
+	bestMove, _ := m.GetBestMoveContext(context.Background(), board, maxDepth)
+	return bestMove
+}
+
+func (m *mcts) GetBestMoveContext(ctx context.Context, board *game.Board, maxDepth int) (bestMove game.Move, err error) {
+	result, err := m.Search(ctx, board, Limits{MaxDepth: maxDepth})
+	return result.BestMove, err
+}
+
+func (m *mcts) GetBestMoveTimed(board *game.Board, moveTime time.Duration, maxDepth int) (bestMove game.Move, iterations []Iteration) {
+	result, _ := m.Search(context.Background(), board, Limits{MaxDepth: maxDepth, MoveTime: moveTime})
+	return result.BestMove, nil
+}
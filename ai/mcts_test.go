@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMCTSPlayer2Win(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 0), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 1), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(0, 1), game.Large))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(0, 0), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 0), game.Large))
+	mcts := NewMCTS(WithSeed(1))
+
+	result, err := mcts.Search(context.Background(), board, Limits{})
+	assert.NoError(t, err)
+	assert.Equal(t, Player2Win, result.Evaluation, "immediate win must be proven")
+
+	board.MustMakeMove(result.BestMove)
+	assert.Equal(t, game.Player2, board.CheckWin(), "best move must win")
+}
+
+func TestMCTSProvenLoss(t *testing.T) {
+	// Every move of Player 1 loses within 4 plies, see solver_test.go
+	board, err := game.ParsePosition("mL,-,-/s,l,mL/s,SM,SMl 1 000/000")
+	assert.NoError(t, err)
+	mcts := NewMCTS(WithSeed(1))
+
+	winner := mcts.CalculateWinner(board, 0)
+	assert.Equal(t, game.Player2, winner, "forced win must be proven by MCTS-Solver")
+}
+
+func TestMCTSDeterministic(t *testing.T) {
+	board := game.NewBoard()
+	first := NewMCTS(WithSeed(7), WithIterations(2000)).GetBestMove(board, 0)
+	second := NewMCTS(WithSeed(7), WithIterations(2000)).GetBestMove(board, 0)
+	assert.Equal(t, game.MoveString(first), game.MoveString(second), "searches with the same seed must choose the same move")
+
+	valid, _ := board.IsValidMove(first)
+	assert.True(t, valid, "best move must be valid")
+}
+
+func TestMCTSMoveTime(t *testing.T) {
+	board := game.NewBoard()
+	mcts := NewMCTS(WithIterations(math.MaxInt), WithRolloutPolicy(RandomRollout))
+
+	start := time.Now()
+	result, err := mcts.Search(context.Background(), board, Limits{MoveTime: 200 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "search must stop after the move time")
+	assert.Positive(t, result.Nodes, "search must run iterations")
+	assert.Equal(t, result.BestMove, result.PV[0], "principal variation must start with the best move")
+}
+
+func TestMCTSCancelledSearch(t *testing.T) {
+	board := game.NewBoard()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewMCTS().Search(ctx, board, Limits{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
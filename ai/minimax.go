@@ -1,62 +1,213 @@
 package ai
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"sort"
+	"time"
 
 	"gibhub.com/bef1993/gobblet-gobblers/game"
 )
 
-// Minimax is the interface for the minimax algorithm
+// Minimax is the interface for the minimax algorithm.
+// A Minimax instance must not be used by multiple goroutines at the same time.
 type Minimax interface {
+	Engine
+
 	CalculateWinner(board *game.Board, maxDepth int) (winner game.Player)
 	GetBestMove(board *game.Board, maxDepth int) game.Move
+	GetBestMoveTimed(board *game.Board, moveTime time.Duration, maxDepth int) (bestMove game.Move, iterations []Iteration)
+
+	// CalculateWinnerContext is like CalculateWinner, but stops when ctx is done.
+	// A cancelled search returns game.None together with ctx.Err().
+	CalculateWinnerContext(ctx context.Context, board *game.Board, maxDepth int) (winner game.Player, err error)
+	// GetBestMoveContext is like GetBestMove, but stops when ctx is done.
+	// A cancelled search returns the best move among the completely searched moves together with ctx.Err().
+	GetBestMoveContext(ctx context.Context, board *game.Board, maxDepth int) (bestMove game.Move, err error)
 }
 
 // minimax struct holds the state for the minimax algorithm
 type minimax struct {
-	ttable    TranspositionTable
-	evaluator Evaluator
+	ttable        TranspositionTable // Allocated by the first search, so engines that never search take no memory
+	ttSize        int                // Memory of the transposition table in MB
+	threads       int                // Number of goroutines of a search, see WithThreads
+	algorithm     Algorithm
+	moveOrdering  MoveOrdering
+	killers       [][killerMoves]game.Move // Killer moves of every ply of the running search
+	history       *historyTable
+	evaluator     Evaluator
+	useSymmetries bool
+	tablebase     *Tablebase
+	openingBook   *OpeningBook
+	ctx           context.Context // Context of the running search, checked at every node
+	aborted       bool
+	draws         int // Number of draws by the draw rules found by the running search
+	stats         SearchStats
+	pv            [][]game.Move // Principal variation of every ply of the running search
+}
+
+// Option configures a Minimax instance
+type Option func(m *minimax)
+
+// WithSymmetries sets whether symmetric positions share their transposition table entries (enabled by default)
+func WithSymmetries(enabled bool) Option {
+	return func(m *minimax) {
+		m.useSymmetries = enabled
+	}
+}
+
+// WithTranspositionTableSize sets the memory of the transposition table in MB (DefaultTTSize by default),
+// at most MaxTTSize
+func WithTranspositionTableSize(sizeMB int) Option {
+	return func(m *minimax) {
+		m.ttSize = sizeMB
+	}
+}
+
+// WithEvaluator sets the evaluator of positions at the end of the search and of moves for move ordering
+// (NewEvaluator by default)
+func WithEvaluator(evaluator Evaluator) Option {
+	return func(m *minimax) {
+		m.evaluator = evaluator
+	}
+}
+
+// WithTablebase lets the search play perfectly in all positions that are part of the tablebase
+func WithTablebase(tablebase *Tablebase) Option {
+	return func(m *minimax) {
+		m.tablebase = tablebase
+	}
+}
+
+// WithOpeningBook lets the search play the stored moves of the opening book without searching
+func WithOpeningBook(book *OpeningBook) Option {
+	return func(m *minimax) {
+		m.openingBook = book
+	}
 }
 
 // NewMinimax creates a new Minimax instance
-func NewMinimax() Minimax {
-	return &minimax{
-		ttable:    NewTranspositionTable(),
-		evaluator: NewEvaluator(),
+func NewMinimax(options ...Option) Minimax {
+	m := &minimax{
+		ttSize:        DefaultTTSize,
+		threads:       1,
+		history:       new(historyTable),
+		evaluator:     NewEvaluator(),
+		useSymmetries: true,
+		ctx:           context.Background(),
 	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
 }
 
 func (m *minimax) CalculateWinner(board *game.Board, maxDepth int) (winner game.Player) {
-	evaluation, _ := m.minimax(board, maxDepth, math.MinInt, math.MaxInt, isMaximizingPlayer(board.ActivePlayer))
-	if evaluation >= Player1Win {
-		return game.Player1
-	} else if evaluation <= Player2Win {
-		return game.Player2
+	winner, _ = m.CalculateWinnerContext(context.Background(), board, maxDepth)
+	return winner
+}
+
+func (m *minimax) CalculateWinnerContext(ctx context.Context, board *game.Board, maxDepth int) (winner game.Player, err error) {
+	result, err := m.searchDepth(ctx, board, maxDepth, nil)
+	if err != nil {
+		return game.None, err
+	}
+
+	if result.Evaluation >= Player1Win {
+		return game.Player1, nil
+	} else if result.Evaluation <= Player2Win {
+		return game.Player2, nil
 	} else {
-		return game.None
+		return game.None, nil
 	}
 }
 
 func (m *minimax) GetBestMove(board *game.Board, maxDepth int) game.Move {
-	eval, bestMove := m.minimax(board, maxDepth, math.MinInt, math.MaxInt, isMaximizingPlayer(board.ActivePlayer))
-	fmt.Printf("Evaluation: %v\n", eval)
+	bestMove, _ := m.GetBestMoveContext(context.Background(), board, maxDepth)
 	return bestMove
 }
 
-func (m *minimax) minimax(board *game.Board, depth, alpha, beta int, isMaximizingPlayer bool) (evaluation int, bestMove game.Move) {
+func (m *minimax) GetBestMoveContext(ctx context.Context, board *game.Board, maxDepth int) (bestMove game.Move, err error) {
+	result, err := m.searchDepth(ctx, board, maxDepth, nil)
+	return result.BestMove, err
+}
+
+// NewGame clears the transposition table and the history of cutoffs, so no knowledge of previous games is kept
+func (m *minimax) NewGame() {
+	if m.ttable != nil {
+		m.ttable.Clear()
+	}
+	*m.history = historyTable{}
+	clear(m.killers)
+}
+
+// startSearch prepares a search that is cancelled when ctx is done and returns a function that resets the search state
+func (m *minimax) startSearch(ctx context.Context) (reset func()) {
+	m.ctx = ctx
+	m.aborted = false
+	m.stats = SearchStats{}
+	if m.ttable == nil {
+		m.ttable = NewTranspositionTable(m.ttSize)
+	}
+	m.ttable.NewSearch()
+	m.ageHistory()
+	return func() {
+		m.ctx = context.Background()
+		m.aborted = false
+	}
+}
+
+// cancelled reports whether the context of the running search is done.
+// Once a search is cancelled, all nodes return immediately without storing their incomplete results in the transposition table.
+func (m *minimax) cancelled() bool {
+	if m.aborted {
+		return true
+	}
+	select {
+	case <-m.ctx.Done():
+		m.aborted = true
+	default:
+	}
+	return m.aborted
+}
+
+// fallbackMove returns the heuristically best move, or an empty move if the game is over
+func (m *minimax) fallbackMove(board *game.Board) game.Move {
+	possibleMoves := board.GetPossibleMoves()
+	if board.CheckLineWin() != game.None || len(possibleMoves) == 0 {
+		return game.Move{}
+	}
+	return m.sortMoves(board, possibleMoves, isMaximizingPlayer(board.ActivePlayer))[0]
+}
+
+func (m *minimax) minimax(board *game.Board, depth, ply, alpha, beta int, isMaximizingPlayer bool) (evaluation int, bestMove game.Move) {
+	m.clearPV(ply)
+	if m.cancelled() {
+		return NoWin, game.Move{}
+	}
+	m.stats.Nodes++
+
+	// Draws by the draw rules depend on the moves leading to the position, so they are neither looked up nor stored.
+	// Nodes with such a draw below them are not stored either, see storeUnlessDrawn.
+	// A line completed by the last move wins even if it also reaches the move limit, like in Board.Outcome.
+	if board.IsDraw() && board.CheckLineWin() == game.None {
+		m.draws++
+		return NoWin, game.Move{}
+	}
 
 	// Check the Transposition Table first
-	if found, evaluation, storedMove := m.ttable.LookupHash(board.Hash, depth, alpha, beta); found {
-		if valid, _ := board.IsValidMove(storedMove); valid {
-			return evaluation, storedMove
+	found, evaluation, hashMove := m.lookup(board, depth, ply, alpha, beta)
+	if found {
+		if valid, _ := board.IsValidMove(hashMove); valid {
+			m.stats.TTCutoffs++
+			m.pv[ply] = append(m.pv[ply], hashMove)
+			return evaluation, hashMove
 		}
 	}
 
-	if board.CheckWin() != game.None {
-		evaluation := m.evaluator.Evaluate(board, depth)
-		m.ttable.StoreHash(board.Hash, evaluation, depth, ExactBound, game.Move{})
+	if board.Outcome().IsOver() {
+		evaluation := m.evaluator.Evaluate(board, ply)
+		m.store(board, evaluation, depth, ply, ExactBound, game.Move{})
 		return evaluation, game.Move{}
 	}
 
@@ -67,47 +218,129 @@ func (m *minimax) minimax(board *game.Board, depth, alpha, beta int, isMaximizin
 	}
 
 	if depth == 0 {
-		evaluation := m.evaluator.Evaluate(board, depth)
-		m.ttable.StoreHash(board.Hash, evaluation, depth, ExactBound, game.Move{})
-		return evaluation, m.sortMoves(board, possibleMoves, isMaximizingPlayer)[0]
+		evaluation := m.evaluator.Evaluate(board, ply)
+		m.store(board, evaluation, depth, ply, ExactBound, game.Move{})
+		return evaluation, m.orderMoves(board, possibleMoves, ply, game.Move{})[0]
 	}
 
 	maxEval := math.MinInt
 	minEval := math.MaxInt
+	originalAlpha, originalBeta := alpha, beta
+	draws := m.draws
 
-	sortedMoves := m.sortMoves(board, possibleMoves, isMaximizingPlayer)
+	sortedMoves := m.orderMoves(board, possibleMoves, ply, hashMove)
 
 	for _, possibleMove := range sortedMoves {
 		board.MustMakeMove(possibleMove)
-		eval, _ := m.minimax(board, depth-1, alpha, beta, !isMaximizingPlayer)
+		eval, _ := m.minimax(board, depth-1, ply+1, alpha, beta, !isMaximizingPlayer)
 		board.MustUndoMove(possibleMove)
 
+		// Results of an aborted search are incomplete and must not be stored
+		if m.aborted {
+			return NoWin, bestMove
+		}
+
 		if isMaximizingPlayer {
 			if eval > maxEval {
 				maxEval = eval
 				bestMove = possibleMove
+				m.updatePV(ply, possibleMove)
 			}
 			alpha = max(alpha, maxEval)
 		} else {
 			if eval < minEval {
 				minEval = eval
 				bestMove = possibleMove
+				m.updatePV(ply, possibleMove)
 			}
 			beta = min(beta, minEval)
 		}
 
 		if beta <= alpha {
+			m.stats.BetaCutoffs++
+			m.recordCutoff(board, possibleMove, depth, ply)
 			break
 		}
 	}
 
+	evaluation = minEval
 	if isMaximizingPlayer {
-		m.ttable.StoreHash(board.Hash, maxEval, depth, LowerBound, bestMove)
-		return maxEval, bestMove
+		evaluation = maxEval
+	}
+	m.storeUnlessDrawn(draws, board, evaluation, depth, ply, boundType(evaluation, originalAlpha, originalBeta), bestMove)
+	return evaluation, bestMove
+}
+
+// boundType determines how an evaluation found within the window (alpha, beta) relates to the exact evaluation
+func boundType(evaluation, alpha, beta int) BoundType {
+	if evaluation <= alpha {
+		return UpperBound
+	}
+	if evaluation >= beta {
+		return LowerBound
+	}
+	return ExactBound
+}
+
+// ttKey returns the key of the board in the transposition table and the symmetry
+// that maps the board onto the orientation in which its entry is stored.
+func (m *minimax) ttKey(board *game.Board) (uint64, game.Symmetry) {
+	if !m.useSymmetries {
+		return board.Hash, game.Identity
+	}
+	return board.CanonicalHash()
+}
+
+// lookup and store convert win scores between the distance from the root of the search and the distance from the
+// position, so entries are valid wherever the position occurs. As the conversion keeps the order of evaluations,
+// the bounds of the search window are converted the same way.
+func (m *minimax) lookup(board *game.Board, depth, ply, alpha, beta int) (found bool, evaluation int, bestMove game.Move) {
+	key, symmetry := m.ttKey(board)
+	found, evaluation, bestMove = m.ttable.LookupHash(key, depth, winScoreToTT(alpha, ply), winScoreToTT(beta, ply))
+	evaluation = winScoreFromTT(evaluation, ply)
+	if found || bestMove != (game.Move{}) {
+		m.stats.TTHits++
 	} else {
-		m.ttable.StoreHash(board.Hash, minEval, depth, UpperBound, bestMove)
-		return minEval, bestMove
+		m.stats.TTMisses++
+	}
+	return found, evaluation, board.TransformMove(bestMove, symmetry.Inverse())
+}
+
+func (m *minimax) store(board *game.Board, evaluation, depth, ply int, entryType BoundType, bestMove game.Move) {
+	key, symmetry := m.ttKey(board)
+	m.ttable.StoreHash(key, winScoreToTT(evaluation, ply), depth, entryType, board.TransformMove(bestMove, symmetry))
+}
+
+// storeUnlessDrawn stores the evaluation of a node unless a draw by the draw rules was found below it since the
+// search found draws times. Such evaluations depend on the moves leading to the position like the draws themselves.
+// Entries stored without a draw below them may still be reused on paths where a draw would be reachable,
+// e.g. closer to the move limit, which the transposition table can not tell apart.
+func (m *minimax) storeUnlessDrawn(draws int, board *game.Board, evaluation, depth, ply int, entryType BoundType, bestMove game.Move) {
+	if m.draws == draws {
+		m.store(board, evaluation, depth, ply, entryType, bestMove)
+	}
+}
+
+// winScoreToTT converts a win score relative to the root into a win score relative to a position ply moves away
+func winScoreToTT(evaluation, ply int) int {
+	switch {
+	case evaluation > Player1Win && evaluation <= Player1Win+MaxWinPlies:
+		return evaluation + ply
+	case evaluation < Player2Win && evaluation >= Player2Win-MaxWinPlies:
+		return evaluation - ply
 	}
+	return evaluation
+}
+
+// winScoreFromTT converts a win score relative to a position ply moves away from the root into a win score relative to the root
+func winScoreFromTT(evaluation, ply int) int {
+	switch {
+	case evaluation > Player1Win:
+		return evaluation - ply
+	case evaluation < Player2Win:
+		return evaluation + ply
+	}
+	return evaluation
 }
 
 func isMaximizingPlayer(player game.Player) bool {
@@ -129,3 +362,15 @@ func (m *minimax) sortMoves(board *game.Board, moves []game.Move, isMaximizingPl
 
 	return moves
 }
+
+// prioritizeMove moves the given move to the front of the moves, e.g. the best move found by a previous search.
+func prioritizeMove(moves []game.Move, move game.Move) []game.Move {
+	for i := range moves {
+		if moves[i] == move {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = move
+			break
+		}
+	}
+	return moves
+}
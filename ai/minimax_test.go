@@ -1,7 +1,10 @@
 package ai
 
 import (
+	"context"
+	"math"
 	"testing"
+	"time"
 
 	"gibhub.com/bef1993/gobblet-gobblers/game"
 	"github.com/stretchr/testify/assert"
@@ -39,9 +42,284 @@ func TestPlayer2Win(t *testing.T) {
 }
 
 func TestFullSolveGame(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solving the start position takes long")
+	}
 	board := game.NewBoard()
 	minimax := NewMinimax()
-	winner := minimax.CalculateWinner(board, 9)
+	// The shortest forced win of Player 1 takes 13 plies
+	result, err := minimax.Search(context.Background(), board, Limits{MaxDepth: 13})
+	assert.NoError(t, err)
 
+	winner, plies := ForcedWin(result.Evaluation)
 	assert.Equal(t, game.Player1, winner, "winner must be Player 1")
+	assert.Equal(t, 13, plies, "the distance to the win must not depend on where positions were stored in the transposition table")
+	assert.Equal(t, 13, result.WinIn)
+}
+
+func TestFastestWin(t *testing.T) {
+	// Player 1 wins immediately on c1 or later in many other ways
+	board, err := game.ParsePosition("L,M,-/s,m,-/-,-,- 1 211/112")
+	assert.NoError(t, err)
+	minimax := NewMinimax()
+
+	for _, limits := range []Limits{{MaxDepth: 5}, {MaxDepth: 7, MoveTime: time.Minute}} {
+		result, err := minimax.Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+		assert.Equal(t, board.Get(0, 2), result.BestMove.To, "the fastest win must be played")
+		winner, plies := ForcedWin(result.Evaluation)
+		assert.Equal(t, game.Player1, winner)
+		assert.Equal(t, 1, plies)
+		assert.Equal(t, 1, result.WinIn)
+	}
+}
+
+func TestForcedWin(t *testing.T) {
+	for _, test := range []struct {
+		evaluation int
+		winner     game.Player
+		plies      int
+		winIn      int
+	}{
+		{WinScore(game.Player1, 0), game.Player1, 0, 0},
+		{WinScore(game.Player1, 13), game.Player1, 13, 13},
+		{WinScore(game.Player2, 4), game.Player2, 4, -4},
+		{Player1Win, game.Player1, -1, 0},
+		{Player2Win, game.Player2, -1, 0},
+		{Player1Win - 1, game.None, 0, 0},
+		{NoWin, game.None, 0, 0},
+	} {
+		winner, plies := ForcedWin(test.evaluation)
+		assert.Equal(t, test.winner, winner, test.evaluation)
+		assert.Equal(t, test.plies, plies, test.evaluation)
+		assert.Equal(t, test.winIn, WinIn(test.evaluation), test.evaluation)
+	}
+	assert.Greater(t, WinScore(game.Player1, 3), WinScore(game.Player1, 5), "faster wins are better")
+	assert.Less(t, WinScore(game.Player2, 3), WinScore(game.Player2, 5), "faster losses are worse")
+}
+
+func TestWinScoreTT(t *testing.T) {
+	for _, evaluation := range []int{NoWin, Player1Win - 1, Player2Win + 1, WinScore(game.Player1, 9), WinScore(game.Player2, 9)} {
+		assert.Equal(t, evaluation, winScoreFromTT(winScoreToTT(evaluation, 4), 4))
+	}
+	// A win 5 plies after a position at ply 4 is a win 3 plies after the same position at ply 2
+	stored := winScoreToTT(WinScore(game.Player2, 9), 4)
+	assert.Equal(t, WinScore(game.Player2, 7), winScoreFromTT(stored, 2))
+	assert.Equal(t, math.MaxInt, winScoreToTT(math.MaxInt, 4), "unbounded search windows stay unbounded")
+}
+
+func TestSymmetriesSameResult(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(0, 1), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 1), game.Large))
+
+	withSymmetries := NewMinimax(WithSymmetries(true)).CalculateWinner(board, 7)
+	withoutSymmetries := NewMinimax(WithSymmetries(false)).CalculateWinner(board, 7)
+	assert.Equal(t, withoutSymmetries, withSymmetries, "symmetry reduction must not change the result")
+}
+
+func TestGetBestMoveTimed(t *testing.T) {
+	board := game.NewBoard()
+	minimax := NewMinimax()
+	start := time.Now()
+	move, iterations := minimax.GetBestMoveTimed(board, 200*time.Millisecond, 20)
+
+	assert.Less(t, time.Since(start), 2*time.Second, "search must stop shortly after the move time")
+	assert.NotEmpty(t, iterations, "at least one iteration must complete")
+	valid, _ := board.IsValidMove(move)
+	assert.True(t, valid, "best move must be valid")
+	assert.Equal(t, iterations[len(iterations)-1].BestMove, move, "best move must be taken from the deepest iteration")
+	for i, iteration := range iterations {
+		assert.Equal(t, i+1, iteration.Depth, "iterations must increase the depth by one")
+	}
+
+	// The search must be usable without time limit afterwards
+	if testing.Short() {
+		t.Skip("solving the start position takes long")
+	}
+	assert.Equal(t, game.Player1, minimax.CalculateWinner(board, 13), "winner must be Player 1")
+}
+
+func TestGetBestMoveTimedSolvedPosition(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 0), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Large))
+	move, iterations := NewMinimax().GetBestMoveTimed(board, time.Minute, 20)
+
+	last := iterations[len(iterations)-1]
+	assert.GreaterOrEqual(t, last.Evaluation, Player1Win, "forced win must be found")
+	assert.Less(t, last.Depth, 20, "search must stop once the game is decided")
+	valid, _ := board.IsValidMove(move)
+	assert.True(t, valid, "best move must be valid")
+}
+
+func TestCancelledSearch(t *testing.T) {
+	board := game.NewBoard()
+	minimax := NewMinimax()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	move, err := minimax.GetBestMoveContext(ctx, board, 9)
+	assert.ErrorIs(t, err, context.Canceled)
+	valid, _ := board.IsValidMove(move)
+	assert.True(t, valid, "cancelled search must return a valid move")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	winner, err := minimax.CalculateWinnerContext(ctx, board, 20)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, game.None, winner, "cancelled search must not report a winner")
+	assert.Less(t, time.Since(start), time.Second, "search must stop shortly after cancellation")
+
+	assert.Equal(t, game.StartPosition, board.Encode(), "board must be restored after a cancelled search")
+
+	// Aborted searches must not leave incomplete results in the transposition table
+	if testing.Short() {
+		t.Skip("solving the start position takes long")
+	}
+	assert.Equal(t, game.Player1, minimax.CalculateWinner(board, 13), "winner must be Player 1")
+}
+
+func TestSearchResult(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 0), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Large))
+
+	result, err := NewMinimax().Search(context.Background(), board, Limits{MaxDepth: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, result.Depth)
+	assert.GreaterOrEqual(t, result.Evaluation, Player1Win, "forced win must be found")
+	assert.Positive(t, result.Nodes, "nodes must be counted")
+	assert.Positive(t, result.BetaCutoffs, "beta cutoffs must be counted")
+	assert.Equal(t, result.Nodes, result.TTHits+result.TTMisses, "every node must look up the transposition table")
+	assert.NotEmpty(t, result.PV, "principal variation must not be empty")
+	assert.Equal(t, result.BestMove, result.PV[0], "principal variation must start with the best move")
+
+	// The principal variation must be playable and lead to the win of Player 1
+	for _, move := range result.PV {
+		assert.NoError(t, board.MakeMove(move))
+	}
+	assert.Equal(t, game.Player1, board.CheckWin(), "principal variation must end with the win of Player 1")
+}
+
+func TestOnIteration(t *testing.T) {
+	var iterations []Iteration
+	limits := Limits{MaxDepth: 4, MoveTime: time.Minute, OnIteration: func(iteration Iteration) {
+		iterations = append(iterations, iteration)
+	}}
+	result, err := NewMinimax().Search(context.Background(), game.NewBoard(), limits)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Iterations, iterations, "every completed iteration must be reported")
+	for i, iteration := range iterations {
+		assert.Equal(t, i+1, iteration.Depth)
+		assert.NotEmpty(t, iteration.PV, "iterations must report their principal variation")
+		assert.Equal(t, iteration.BestMove, iteration.PV[0])
+		assert.Positive(t, iteration.Nodes)
+	}
+	assert.Equal(t, result.PV, iterations[len(iterations)-1].PV)
+
+	iterations = nil
+	limits.MoveTime = 0
+	result, err = NewMinimax().Search(context.Background(), game.NewBoard(), limits)
+	assert.NoError(t, err)
+	assert.Len(t, iterations, 1, "a search to a fixed depth must be reported once")
+	assert.Equal(t, result.PV, iterations[0].PV)
+}
+
+func TestMoveLimitPreventsWin(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 0), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Large))
+	// The forced win of Player 1 takes more plies than the game has left
+	board.DrawRules.MaxPlies = board.Plies() + 4
+
+	winner := NewMinimax().CalculateWinner(board, 8)
+	assert.Equal(t, game.None, winner, "game must not be won after the move limit")
+}
+
+func TestWinAtMoveLimit(t *testing.T) {
+	// Player 1 wins on c1 with the last move before the move limit
+	board, err := game.ParsePosition("L,M,-/s,m,-/-,-,- 1 211/112")
+	assert.NoError(t, err)
+	board.DrawRules.MaxPlies = board.Plies() + 1
+
+	for _, algorithm := range []Algorithm{AlphaBeta, PVS} {
+		result, err := NewMinimax(WithAlgorithm(algorithm)).Search(context.Background(), board, Limits{MaxDepth: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, WinScore(game.Player1, 1), result.Evaluation, "a line completed by the last move must win, %s", algorithm)
+		assert.Equal(t, board.Get(0, 2), result.BestMove.To)
+	}
+}
+
+func TestEvaluateDraw(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(0, 0), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 1), game.Small))
+	board.DrawRules.MaxPlies = board.Plies()
+
+	assert.Equal(t, game.Drawn, board.Outcome())
+	assert.Equal(t, NoWin, NewEvaluator().Evaluate(board, 3), "draws must be evaluated as neither player winning")
+}
+
+func TestPiecesEvaluator(t *testing.T) {
+	board, err := game.ParsePosition("L,-,-/-,s,-/-,-,M 2 211/122")
+	assert.NoError(t, err)
+	evaluator := NewPiecesEvaluator()
+	assert.Equal(t, 30+20-10, evaluator.Evaluate(board, 0), "visible pieces are rated by their size")
+
+	result, err := NewMinimax(WithEvaluator(evaluator)).Search(context.Background(), board, Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+	valid, _ := board.IsValidMove(result.BestMove)
+	assert.True(t, valid)
+}
+
+func TestNewGame(t *testing.T) {
+	board := game.NewBoard()
+	minimax := NewMinimax(WithMoveOrdering(HistoryOrdering)).(*minimax)
+	minimax.NewGame()
+	assert.Nil(t, minimax.ttable, "the transposition table must be allocated by the first search")
+	_, err := minimax.Search(context.Background(), board, Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+	found, _, _ := minimax.lookup(board, 3, 0, -infinity, infinity)
+	assert.True(t, found)
+
+	minimax.NewGame()
+	found, _, bestMove := minimax.lookup(board, 0, 0, -infinity, infinity)
+	assert.False(t, found, "the transposition table must be empty in a new game")
+	assert.Equal(t, game.Move{}, bestMove)
+	assert.Equal(t, historyTable{}, *minimax.history)
+}
+
+func TestGobbletRules(t *testing.T) {
+	board := game.NewBoardWithRules(game.GobbletRules)
+	for col := 0; col < 3; col++ {
+		board.MustMakeMove(game.NewMove(game.Player1, board.Get(0, col), game.ExtraLarge))
+		board.MustMakeMove(game.NewMove(game.Player2, board.Get(3, col), game.ExtraLarge))
+	}
+
+	bestMove := NewMinimax().GetBestMove(board, 2)
+	board.MustMakeMove(bestMove)
+	assert.Equal(t, game.Player1, board.CheckWin(), "minimax must complete the row of four")
+}
+
+func TestUncoverLoses(t *testing.T) {
+	rules := game.DefaultRules
+	rules.Uncover = game.UncoverLoses
+	// Lifting the large piece on a1 uncovers the first row of Player 2
+	board, err := game.ParsePositionWithRules("sL,m,s/-,-,-/-,-,- 1 221/012", rules)
+	assert.NoError(t, err)
+
+	result, err := NewMinimax().Search(context.Background(), board, Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+	assert.NotEqual(t, board.Get(0, 0), result.BestMove.From, "moving the large piece loses immediately")
+	assert.Greater(t, result.Evaluation, Player2Win)
+
+	move := game.NewMoveExisting(board.Get(0, 0), board.Get(2, 2))
+	board.MustMakeMove(move)
+	assert.Equal(t, game.Player2, board.CheckWin())
+	assert.Equal(t, game.Player2, NewMinimax().CalculateWinner(board, 1))
 }
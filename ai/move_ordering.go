@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"cmp"
+	"slices"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// MoveOrdering selects the order in which the moves of a position are searched after the best move of the
+// transposition table. Good orderings search the best move first and cause more alpha-beta cutoffs.
+type MoveOrdering int
+
+const (
+	// HeuristicOrdering sorts the moves by the heuristic evaluation of the position after the move
+	HeuristicOrdering MoveOrdering = iota
+	// HistoryOrdering searches the killer moves of the ply first, which caused cutoffs in sibling positions, and then
+	// the moves that caused the most cutoffs in the whole search according to the history table. It does not make any
+	// moves to order them, which makes every node much cheaper, but the order is worse: it pays off in shallow
+	// searches only, solving the start position takes more than ten times longer than with HeuristicOrdering.
+	HistoryOrdering
+)
+
+var moveOrderingNames = []string{"heuristic", "history"}
+
+func (o MoveOrdering) String() string {
+	return moveOrderingNames[o]
+}
+
+// WithMoveOrdering selects the move ordering (HeuristicOrdering by default)
+func WithMoveOrdering(ordering MoveOrdering) Option {
+	return func(m *minimax) {
+		m.moveOrdering = ordering
+	}
+}
+
+const (
+	killerMoves = 2
+	// killerScore ranks killer moves before all moves of the history table
+	killerScore = 1 << 30
+)
+
+// historyTable counts the cutoffs caused by a move of a player, indexed by the origin (noSquare for placing a piece),
+// the destination and the size of the piece. Cutoffs far from the leaves count more, as they prune larger subtrees.
+type historyTable [game.Player2 + 1][noSquare + 1][game.MaxBoardSize * game.MaxBoardSize][game.MaxPieceSizes]int
+
+// orderMoves orders the moves of the board, with the best move of the transposition table first
+func (m *minimax) orderMoves(board *game.Board, moves []game.Move, ply int, hashMove game.Move) []game.Move {
+	if m.moveOrdering == HeuristicOrdering {
+		return prioritizeMove(m.sortMoves(board, moves, isMaximizingPlayer(board.ActivePlayer)), hashMove)
+	}
+
+	killers := m.killersOf(ply)
+	scored := make([]scoredMove, len(moves))
+	for i, move := range moves {
+		scored[i].move = move
+		switch move {
+		case killers[0]:
+			scored[i].score = killerScore + 1
+		case killers[1]:
+			scored[i].score = killerScore
+		default:
+			scored[i].score = min(*m.historyEntry(board, move), killerScore-1)
+		}
+	}
+	slices.SortStableFunc(scored, func(a, b scoredMove) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	for i := range scored {
+		moves[i] = scored[i].move
+	}
+	return prioritizeMove(moves, hashMove)
+}
+
+type scoredMove struct {
+	move  game.Move
+	score int
+}
+
+// recordCutoff remembers a move that caused a cutoff at the ply with the given remaining depth.
+// The move must not be made on the board.
+func (m *minimax) recordCutoff(board *game.Board, move game.Move, depth, ply int) {
+	if m.moveOrdering != HistoryOrdering {
+		return
+	}
+	if killers := m.killersOf(ply); killers[0] != move {
+		killers[1], killers[0] = killers[0], move
+	}
+	*m.historyEntry(board, move) += depth * depth
+}
+
+// killersOf returns the killer moves of the ply, which refer to the positions of the board of the running search
+func (m *minimax) killersOf(ply int) *[killerMoves]game.Move {
+	for len(m.killers) <= ply {
+		m.killers = append(m.killers, [killerMoves]game.Move{})
+	}
+	return &m.killers[ply]
+}
+
+func (m *minimax) historyEntry(board *game.Board, move game.Move) *int {
+	size := move.Piece.Size
+	if move.MovesExistingPiece() {
+		size = move.From.TopPiece().Size
+	}
+	return &m.history[board.ActivePlayer][squareIndex(move.From)][squareIndex(move.To)][size]
+}
+
+// ageHistory clears the killer moves, which refer to the previous board, and halves the history table,
+// so the cutoffs of the new search soon outweigh the cutoffs of previous searches
+func (m *minimax) ageHistory() {
+	clear(m.killers)
+	for player := range m.history {
+		for from := range m.history[player] {
+			for to := range m.history[player][from] {
+				for size := range m.history[player][from][to] {
+					m.history[player][from][to][size] /= 2
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveOrderingSameEvaluation(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlphaBeta, PVS} {
+		for _, test := range searchSuite {
+			board, err := game.ParsePositionWithRules(test.position, test.rules)
+			if !assert.NoError(t, err, test.position) {
+				continue
+			}
+			limits := Limits{MaxDepth: min(test.depth, shallowDepth)}
+
+			heuristic, err := NewMinimax(WithAlgorithm(algorithm)).Search(context.Background(), board, limits)
+			assert.NoError(t, err)
+			history, err := NewMinimax(WithAlgorithm(algorithm), WithMoveOrdering(HistoryOrdering)).
+				Search(context.Background(), board, limits)
+			assert.NoError(t, err)
+
+			assert.Equal(t, heuristic.Evaluation, history.Evaluation, "%s %s", algorithm, test.position)
+			valid, _ := board.IsValidMove(history.BestMove)
+			assert.True(t, valid, test.position)
+		}
+	}
+}
+
+// BenchmarkMoveOrdering reports the nodes and the time of both move orderings on the search suite, e.g.
+// go test ./ai -run none -bench MoveOrdering
+func BenchmarkMoveOrdering(b *testing.B) {
+	for _, algorithm := range []Algorithm{AlphaBeta, PVS} {
+		b.Run(fmt.Sprintf("%s/heuristic", algorithm), func(b *testing.B) {
+			benchmarkSearchSuite(b, WithAlgorithm(algorithm))
+		})
+		b.Run(fmt.Sprintf("%s/history", algorithm), func(b *testing.B) {
+			benchmarkSearchSuite(b, WithAlgorithm(algorithm), WithMoveOrdering(HistoryOrdering))
+		})
+	}
+}
+
+func TestKillerMoves(t *testing.T) {
+	m := NewMinimax(WithMoveOrdering(HistoryOrdering)).(*minimax)
+	board := game.NewBoard()
+	moves := board.GetPossibleMoves()
+	first, second := moves[5], moves[9]
+
+	m.recordCutoff(board, first, 3, 2)
+	m.recordCutoff(board, second, 1, 2)
+	m.recordCutoff(board, second, 1, 2)
+	ordered := m.orderMoves(board, board.GetPossibleMoves(), 2, game.Move{})
+	assert.Equal(t, []game.Move{second, first}, ordered[:2], "the latest killer move is searched first")
+
+	ordered = m.orderMoves(board, board.GetPossibleMoves(), 2, moves[3])
+	assert.Equal(t, []game.Move{moves[3], second, first}, ordered[:3], "the move of the transposition table is searched first")
+
+	ordered = m.orderMoves(board, board.GetPossibleMoves(), 1, game.Move{})
+	assert.Equal(t, []game.Move{first, second}, ordered[:2], "other plies are ordered by the history table")
+
+	m.ageHistory()
+	assert.Equal(t, 9/2, *m.historyEntry(board, first), "the history of previous searches is halved")
+	assert.Equal(t, game.Move{}, m.killersOf(2)[0], "killer moves are cleared")
+}
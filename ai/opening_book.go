@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// BookEntry is the stored search result of a position in the opening book
+type BookEntry struct {
+	Move       string // Best move in the notation of game.MoveString, relative to the canonical orientation of the position
+	Evaluation int
+	Depth      int
+}
+
+// OpeningBook stores precomputed best moves of opening positions of the same rules, keyed by canonical hash
+type OpeningBook struct {
+	rules   game.Rules
+	entries map[uint64]BookEntry
+}
+
+// NewOpeningBook creates an empty opening book for positions of the rules
+func NewOpeningBook(rules game.Rules) *OpeningBook {
+	return &OpeningBook{rules: rules, entries: make(map[uint64]BookEntry)}
+}
+
+// Len returns the number of positions in the opening book
+func (o *OpeningBook) Len() int {
+	return len(o.entries)
+}
+
+// Rules returns the rules of the positions in the opening book
+func (o *OpeningBook) Rules() game.Rules {
+	return o.rules
+}
+
+// Add stores the best move of the board
+func (o *OpeningBook) Add(board *game.Board, bestMove game.Move, evaluation, depth int) {
+	key, symmetry := board.CanonicalHash()
+	o.entries[key] = BookEntry{
+		Move:       game.MoveString(board.TransformMove(bestMove, symmetry)),
+		Evaluation: evaluation,
+		Depth:      depth,
+	}
+}
+
+// Lookup returns the best move of the board and its entry, found is false if the position is not part of the book,
+// e.g. because it is played with other rules
+func (o *OpeningBook) Lookup(board *game.Board) (bestMove game.Move, entry BookEntry, found bool) {
+	if board.Rules != o.rules {
+		return game.Move{}, entry, false
+	}
+	key, symmetry := board.CanonicalHash()
+	entry, found = o.entries[key]
+	if !found {
+		return game.Move{}, entry, false
+	}
+
+	move, err := game.ParseMove(entry.Move, board)
+	if err != nil {
+		return game.Move{}, entry, false
+	}
+	bestMove = board.TransformMove(move, symmetry.Inverse())
+	if valid, _ := board.IsValidMove(bestMove); !valid {
+		return game.Move{}, entry, false
+	}
+	return bestMove, entry, true
+}
+
+// GenerateOpeningBook searches every position that is reachable from the board within the given number of plies
+// and stores the best moves in an opening book. Symmetric positions are only searched once.
+func GenerateOpeningBook(ctx context.Context, board *game.Board, plies int, engine Engine, limits Limits) (*OpeningBook, error) {
+	book := NewOpeningBook(board.Rules)
+	seen := make(map[uint64]bool)
+	positions := []string{board.Encode()}
+
+	for ply := 0; ply < plies && len(positions) > 0; ply++ {
+		var nextPositions []string
+		for _, position := range positions {
+			current, err := game.ParsePositionWithRules(position, board.Rules)
+			if err != nil {
+				return nil, err
+			}
+			if current.Outcome().IsOver() {
+				continue
+			}
+
+			result, err := engine.Search(ctx, current, limits)
+			if err != nil {
+				return nil, err
+			}
+			book.Add(current, result.BestMove, result.Evaluation, result.Depth)
+
+			for _, move := range current.GetPossibleMoves() {
+				current.MustMakeMove(move)
+				if key, _ := current.CanonicalHash(); !seen[key] {
+					seen[key] = true
+					nextPositions = append(nextPositions, current.Encode())
+				}
+				current.MustUndoMove(move)
+			}
+		}
+		positions = nextPositions
+	}
+	return book, nil
+}
+
+// Write writes a header line with the rules followed by one line per position: the canonical hash in hex, the best
+// move, the evaluation and the search depth
+func (o *OpeningBook) Write(w io.Writer) error {
+	keys := make([]uint64, 0, len(o.entries))
+	for key := range o.entries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	writer := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(writer, "%s %v\n", bookRulesPrefix, o.rules); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		entry := o.entries[key]
+		if _, err := fmt.Fprintf(writer, "%016x %s %d %d\n", key, entry.Move, entry.Evaluation, entry.Depth); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// bookRulesPrefix starts the header line of an opening book file, which names the rules of the book
+const bookRulesPrefix = "rules"
+
+// ReadOpeningBook reads an opening book written by OpeningBook.Write, which must have been generated for the rules
+func ReadOpeningBook(r io.Reader, rules game.Rules) (*OpeningBook, error) {
+	book := NewOpeningBook(rules)
+	scanner := bufio.NewScanner(r)
+	header := false
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if !header {
+			if len(fields) != 2 || fields[0] != bookRulesPrefix {
+				return nil, fmt.Errorf("line %d: expected the rules of the opening book", line)
+			}
+			if err := checkRules(fields[1], rules); err != nil {
+				return nil, fmt.Errorf("opening book %w", err)
+			}
+			header = true
+			continue
+		}
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: expected hash, move, evaluation and depth", line)
+		}
+
+		key, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hash: %w", line, err)
+		}
+		evaluation, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid evaluation: %w", line, err)
+		}
+		depth, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid depth: %w", line, err)
+		}
+		book.entries[key] = BookEntry{Move: fields[1] + " " + fields[2], Evaluation: evaluation, Depth: depth}
+	}
+	return book, scanner.Err()
+}
+
+// SaveOpeningBook writes the opening book to the file at path
+func SaveOpeningBook(path string, book *OpeningBook) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := book.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadOpeningBook reads the opening book of the rules from the file at path
+func LoadOpeningBook(path string, rules game.Rules) (*OpeningBook, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadOpeningBook(file, rules)
+}
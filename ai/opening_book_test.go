@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateOpeningBook(t *testing.T) {
+	board := game.NewBoard()
+	book, err := GenerateOpeningBook(context.Background(), board, 2, NewMinimax(), Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+	// Start position and the first moves of Player 1: 3 sizes on a corner, an edge or the center
+	assert.Equal(t, 1+3*3, book.Len())
+
+	// All rotations of a position must find the book move
+	for _, s := range []game.Symmetry{game.Identity, game.Rotate90, game.FlipDiagonal} {
+		row, col := s.Transform(0, 1, board.Rules.BoardSize)
+		board.MustMakeMove(game.NewMove(game.Player1, board.Get(row, col), game.Medium))
+
+		move, entry, found := book.Lookup(board)
+		assert.True(t, found, "position must be part of the opening book")
+		assert.Equal(t, 3, entry.Depth)
+		valid, _ := board.IsValidMove(move)
+		assert.True(t, valid, "book move must be valid")
+
+		result, err := NewMinimax().Search(context.Background(), board, Limits{MaxDepth: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, result.Evaluation, entry.Evaluation, "book must store the search evaluation")
+
+		board = game.NewBoard()
+	}
+}
+
+func TestOpeningBookRoundTrip(t *testing.T) {
+	book, err := GenerateOpeningBook(context.Background(), game.NewBoard(), 2, NewMinimax(), Limits{MaxDepth: 2})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, book.Write(&buf))
+	written := buf.String()
+	read, err := ReadOpeningBook(&buf, game.DefaultRules)
+	assert.NoError(t, err)
+	assert.Equal(t, book, read, "read opening book must be equal to the written opening book")
+
+	_, err = ReadOpeningBook(strings.NewReader(written), game.GobbletRules)
+	assert.ErrorContains(t, err, "is for the rules standard", "opening books of other rules must be rejected")
+
+	_, err = ReadOpeningBook(bytes.NewReader([]byte("rules standard\nxyz b2 L 10 9\n")), game.DefaultRules)
+	assert.Error(t, err)
+	_, err = ReadOpeningBook(bytes.NewReader([]byte("0000000000000001 b2 L 10 9\n")), game.DefaultRules)
+	assert.Error(t, err, "opening books without rules must be rejected")
+}
+
+func TestMinimaxWithOpeningBook(t *testing.T) {
+	board := game.NewBoard()
+	book := NewOpeningBook(game.DefaultRules)
+	bookMove := game.NewMove(game.Player1, board.Get(2, 0), game.Small)
+	book.Add(board, bookMove, 42, 13)
+
+	result, err := NewMinimax(WithOpeningBook(book)).Search(context.Background(), board, Limits{MaxDepth: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, bookMove, result.BestMove, "book move must be played")
+	assert.Equal(t, 42, result.Evaluation)
+	assert.Zero(t, result.Nodes, "book moves must be played without searching")
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"sync"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// WithThreads sets the number of goroutines that search in parallel (1 by default).
+// Additional threads use Lazy SMP: they search copies of the board with increasing depth and share the transposition
+// table with the main search, which finds more cutoffs in the entries they store. The result is always the result of
+// the main search, but it depends on the timing of the helpers. Searches with a single thread are deterministic.
+func WithThreads(threads int) Option {
+	return func(m *minimax) {
+		m.threads = max(threads, 1)
+	}
+}
+
+// startHelpers starts the helper threads of a search up to maxDepth. The context of the search must be set by startSearch.
+// The returned function stops the helpers and returns the statistics of their searches.
+func (m *minimax) startHelpers(board *game.Board, maxDepth int) (stop func() SearchStats) {
+	if m.threads <= 1 {
+		return func() SearchStats { return SearchStats{} }
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	var wg sync.WaitGroup
+	stats := make([]SearchStats, m.threads-1)
+	for i := range stats {
+		// A copy keeps the configuration of the engine, e.g. its tablebase, and resets the state of the running search
+		helper := new(minimax)
+		*helper = *m
+		helper.ctx, helper.aborted, helper.draws, helper.stats = ctx, false, 0, SearchStats{}
+		helper.pv, helper.killers, helper.history = nil, nil, new(historyTable)
+		board := board.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			helper.helperSearch(board, maxDepth, i)
+			stats[i] = helper.stats
+		}()
+	}
+
+	return func() SearchStats {
+		cancel()
+		wg.Wait()
+		total := SearchStats{}
+		for _, s := range stats {
+			total.add(s)
+		}
+		return total
+	}
+}
+
+// helperSearch searches the board with increasing depth until maxDepth is reached or the helper is stopped.
+// Every other helper starts one ply deeper, so the helpers do not all search the same depth at the same time.
+func (m *minimax) helperSearch(board *game.Board, maxDepth, helper int) {
+	for depth := 1 + helper%2; depth <= maxDepth && !m.cancelled(); depth++ {
+		evaluation, _ := m.search(board, depth, -infinity, infinity)
+		if evaluation >= Player1Win || evaluation <= Player2Win {
+			return
+		}
+	}
+}
+
+func (s *SearchStats) add(other SearchStats) {
+	s.Nodes += other.Nodes
+	s.TTHits += other.TTHits
+	s.TTMisses += other.TTMisses
+	s.TTCutoffs += other.TTCutoffs
+	s.BetaCutoffs += other.BetaCutoffs
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelSearch(t *testing.T) {
+	board := game.NewBoard()
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Small))
+	board.MustMakeMove(game.NewMove(game.Player2, board.Get(1, 0), game.Medium))
+	board.MustMakeMove(game.NewMove(game.Player1, board.Get(1, 1), game.Large))
+	encoded := board.Encode()
+
+	single, err := NewMinimax().Search(context.Background(), board, Limits{MaxDepth: 8})
+	assert.NoError(t, err)
+	parallel, err := NewMinimax(WithThreads(4)).Search(context.Background(), board, Limits{MaxDepth: 8})
+	assert.NoError(t, err)
+
+	assert.Equal(t, single.Evaluation, parallel.Evaluation, "helpers must not change the evaluation")
+	assert.Equal(t, encoded, board.Encode(), "helpers must search copies of the board")
+	valid, _ := board.IsValidMove(parallel.BestMove)
+	assert.True(t, valid)
+
+	timed, err := NewMinimax(WithThreads(4)).Search(context.Background(), game.NewBoard(), Limits{MaxDepth: 30, MoveTime: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Greater(t, timed.Depth, 0)
+	assert.Greater(t, timed.Nodes, 0)
+}
+
+func TestSingleThreadDeterministic(t *testing.T) {
+	first, _ := NewMinimax().Search(context.Background(), game.NewBoard(), Limits{MaxDepth: 5})
+	second, _ := NewMinimax().Search(context.Background(), game.NewBoard(), Limits{MaxDepth: 5})
+	first.Elapsed, second.Elapsed = 0, 0
+	assert.Equal(t, first, second)
+}
+
+// BenchmarkFullSolve shows how the nodes per second of solving the start position scale with the number of threads
+func BenchmarkFullSolve(b *testing.B) {
+	for _, threads := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			var nodes int
+			var elapsed time.Duration
+			for range b.N {
+				result, _ := NewMinimax(WithThreads(threads)).Search(context.Background(), game.NewBoard(), Limits{MaxDepth: 13})
+				nodes += result.Nodes
+				elapsed += result.Elapsed
+			}
+			b.ReportMetric(float64(nodes)/elapsed.Seconds(), "nodes/s")
+		})
+	}
+}
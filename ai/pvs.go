@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"math"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Algorithm selects the search algorithm of Minimax
+type Algorithm int
+
+const (
+	// AlphaBeta is minimax with alpha-beta pruning, with separate branches for the maximizing and the minimizing player
+	AlphaBeta Algorithm = iota
+	// PVS is a negamax principal variation search: after the first move, all moves are searched with a null window
+	// that only proves them worse, and searched again if they are not. Iterative deepening starts every iteration
+	// with an aspiration window around the evaluation of the previous iteration.
+	PVS
+)
+
+var algorithmNames = []string{"alphabeta", "pvs"}
+
+func (a Algorithm) String() string {
+	return algorithmNames[a]
+}
+
+// WithAlgorithm selects the search algorithm (AlphaBeta by default)
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(m *minimax) {
+		m.algorithm = algorithm
+	}
+}
+
+const (
+	// infinity bounds a full search window. Unlike math.MinInt, its negation does not overflow.
+	infinity = math.MaxInt
+	// aspirationWindow is the initial distance of the bounds of an aspiration window from the previous evaluation
+	aspirationWindow = 25
+)
+
+// search searches the board from the root with the selected algorithm. The window and the evaluation are given
+// from the perspective of Player 1.
+func (m *minimax) search(board *game.Board, depth, alpha, beta int) (evaluation int, bestMove game.Move) {
+	if m.algorithm != PVS {
+		return m.minimax(board, depth, 0, alpha, beta, isMaximizingPlayer(board.ActivePlayer))
+	}
+	sign := playerSign(board.ActivePlayer)
+	alpha, beta = perspectiveWindow(sign, alpha, beta)
+	score, bestMove := m.pvs(board, depth, 0, alpha, beta)
+	return sign * score, bestMove
+}
+
+// aspirationSearch searches an iteration of iterative deepening. PVS searches a narrow window around the evaluation
+// of the previous iteration first, which is widened until the evaluation lies within the window.
+func (m *minimax) aspirationSearch(board *game.Board, depth, previous int) (evaluation int, bestMove game.Move) {
+	if m.algorithm != PVS || depth == 1 {
+		return m.search(board, depth, -infinity, infinity)
+	}
+	for delta := aspirationWindow; ; delta *= 4 {
+		alpha, beta := -infinity, infinity
+		if delta < Player1Win {
+			alpha, beta = previous-delta, previous+delta
+		}
+		evaluation, bestMove = m.search(board, depth, alpha, beta)
+		if m.aborted || (evaluation > alpha && evaluation < beta) {
+			return evaluation, bestMove
+		}
+	}
+}
+
+// pvs is the negamax variant of minimax: scores and the window (alpha, beta) are given from the perspective of the
+// player to move, the transposition table stores evaluations from the perspective of Player 1 like minimax.
+func (m *minimax) pvs(board *game.Board, depth, ply, alpha, beta int) (score int, bestMove game.Move) {
+	m.clearPV(ply)
+	if m.cancelled() {
+		return NoWin, game.Move{}
+	}
+	m.stats.Nodes++
+
+	// Draws by the draw rules depend on the moves leading to the position, so they are neither looked up nor stored.
+	// Nodes with such a draw below them are not stored either, see storeUnlessDrawn.
+	// A line completed by the last move wins even if it also reaches the move limit, like in Board.Outcome.
+	if board.IsDraw() && board.CheckLineWin() == game.None {
+		m.draws++
+		return NoWin, game.Move{}
+	}
+
+	sign := playerSign(board.ActivePlayer)
+	windowAlpha, windowBeta := perspectiveWindow(sign, alpha, beta)
+	found, evaluation, hashMove := m.lookup(board, depth, ply, windowAlpha, windowBeta)
+	if found {
+		if valid, _ := board.IsValidMove(hashMove); valid {
+			m.stats.TTCutoffs++
+			m.pv[ply] = append(m.pv[ply], hashMove)
+			return sign * evaluation, hashMove
+		}
+	}
+
+	if board.Outcome().IsOver() {
+		evaluation := m.evaluator.Evaluate(board, ply)
+		m.store(board, evaluation, depth, ply, ExactBound, game.Move{})
+		return sign * evaluation, game.Move{}
+	}
+
+	possibleMoves := board.GetPossibleMoves()
+
+	if len(possibleMoves) == 0 {
+		panic("when no moves are possible the game must be lost for the current player")
+	}
+
+	if depth == 0 {
+		evaluation := m.evaluator.Evaluate(board, ply)
+		m.store(board, evaluation, depth, ply, ExactBound, game.Move{})
+		return sign * evaluation, m.orderMoves(board, possibleMoves, ply, game.Move{})[0]
+	}
+
+	bestScore := -infinity
+	draws := m.draws
+	sortedMoves := m.orderMoves(board, possibleMoves, ply, hashMove)
+
+	for i, possibleMove := range sortedMoves {
+		board.MustMakeMove(possibleMove)
+		var score int
+		if i == 0 {
+			score, _ = m.pvs(board, depth-1, ply+1, -beta, -alpha)
+			score = -score
+		} else {
+			// Prove that the move is not better than the best move so far, and search it again if it is
+			score, _ = m.pvs(board, depth-1, ply+1, -alpha-1, -alpha)
+			score = -score
+			if score > alpha && score < beta && !m.aborted {
+				score, _ = m.pvs(board, depth-1, ply+1, -beta, -alpha)
+				score = -score
+			}
+		}
+		board.MustUndoMove(possibleMove)
+
+		// Results of an aborted search are incomplete and must not be stored
+		if m.aborted {
+			return NoWin, bestMove
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = possibleMove
+			m.updatePV(ply, possibleMove)
+		}
+		alpha = max(alpha, bestScore)
+
+		if alpha >= beta {
+			m.stats.BetaCutoffs++
+			m.recordCutoff(board, possibleMove, depth, ply)
+			break
+		}
+	}
+
+	evaluation = sign * bestScore
+	m.storeUnlessDrawn(draws, board, evaluation, depth, ply, boundType(evaluation, windowAlpha, windowBeta), bestMove)
+	return bestScore, bestMove
+}
+
+// playerSign is 1 for Player 1 and -1 for Player 2, the factor that converts evaluations into the perspective of the player
+func playerSign(player game.Player) int {
+	if isMaximizingPlayer(player) {
+		return 1
+	}
+	return -1
+}
+
+// perspectiveWindow converts a window between the perspective of Player 1 and the perspective of the player of the sign
+func perspectiveWindow(sign, alpha, beta int) (int, int) {
+	if sign > 0 {
+		return alpha, beta
+	}
+	return -beta, -alpha
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+// searchSuite are positions of all phases of the game with the depth they are searched to
+var searchSuite = []struct {
+	position string
+	rules    game.Rules
+	depth    int
+}{
+	{"-,-,-/-,-,-/-,-,- 1 222/222", game.DefaultRules, 6},
+	{"-,-,-/-,S,-/-,-,- 2 122/222", game.DefaultRules, 6},
+	{"-,-,-/m,SL,-/-,-,- 2 121/212", game.DefaultRules, 8},
+	{"-,m,-/-,L,-/-,-,- 1 221/212", game.DefaultRules, 7},
+	{"L,M,-/s,m,-/-,-,- 1 211/112", game.DefaultRules, 6},
+	{"sL,m,s/M,M,-/-,-,- 1 201/012", game.DefaultRules, 6},
+	{"-,-,-,-/-,-,-,-/-,-,-,-/-,-,-,- 1 3333/3333", game.GobbletRules, 3},
+}
+
+// shallowDepth limits the depth of the search suite in unit tests, the full depths are searched by the benchmarks
+const shallowDepth = 4
+
+func TestPVSSameEvaluation(t *testing.T) {
+	for _, test := range searchSuite {
+		board, err := game.ParsePositionWithRules(test.position, test.rules)
+		if !assert.NoError(t, err, test.position) {
+			continue
+		}
+		limits := Limits{MaxDepth: min(test.depth, shallowDepth)}
+
+		alphaBeta, err := NewMinimax().Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+		pvs, err := NewMinimax(WithAlgorithm(PVS)).Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+
+		assert.Equal(t, alphaBeta.Evaluation, pvs.Evaluation, test.position)
+		valid, _ := board.IsValidMove(pvs.BestMove)
+		assert.True(t, valid, test.position)
+	}
+}
+
+func TestPVSPrunesMoreNodes(t *testing.T) {
+	board, err := game.ParsePosition("-,-,-/m,SL,-/-,-,- 2 121/212")
+	assert.NoError(t, err)
+
+	alphaBeta, err := NewMinimax().Search(context.Background(), board, Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+	pvs, err := NewMinimax(WithAlgorithm(PVS)).Search(context.Background(), board, Limits{MaxDepth: 3})
+	assert.NoError(t, err)
+
+	assert.Equal(t, alphaBeta.Evaluation, pvs.Evaluation)
+	assert.Less(t, pvs.Nodes, alphaBeta.Nodes, "null windows must prune more nodes than the full window")
+}
+
+func TestPVSIterativeDeepening(t *testing.T) {
+	for _, test := range searchSuite {
+		board, err := game.ParsePositionWithRules(test.position, test.rules)
+		if !assert.NoError(t, err, test.position) {
+			continue
+		}
+		limits := Limits{MaxDepth: min(test.depth, shallowDepth), MoveTime: time.Minute}
+
+		alphaBeta, err := NewMinimax().Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+		pvs, err := NewMinimax(WithAlgorithm(PVS)).Search(context.Background(), board, limits)
+		assert.NoError(t, err)
+
+		assert.Equal(t, alphaBeta.Depth, pvs.Depth, test.position)
+		assert.Equal(t, alphaBeta.Evaluation, pvs.Evaluation, test.position)
+		for i := range pvs.Iterations {
+			assert.Equal(t, alphaBeta.Iterations[i].Evaluation, pvs.Iterations[i].Evaluation, "aspiration windows must not change the evaluation")
+		}
+	}
+}
+
+// BenchmarkPVS reports the nodes that alpha-beta and PVS visit on the search suite, e.g.
+// go test ./ai -run none -bench PVS
+func BenchmarkPVS(b *testing.B) {
+	b.Run("alphabeta", func(b *testing.B) { benchmarkSearchSuite(b) })
+	b.Run("pvs", func(b *testing.B) { benchmarkSearchSuite(b, WithAlgorithm(PVS)) })
+}
+
+// benchmarkSearchSuite searches all positions of the search suite to their full depth with new engines and reports
+// the visited nodes per run
+func benchmarkSearchSuite(b *testing.B, options ...Option) {
+	boards := make([]*game.Board, len(searchSuite))
+	for i, test := range searchSuite {
+		board, err := game.ParsePositionWithRules(test.position, test.rules)
+		if err != nil {
+			b.Fatal(err)
+		}
+		boards[i] = board
+	}
+
+	nodes := 0
+	for range b.N {
+		for i, test := range searchSuite {
+			result, _ := NewMinimax(options...).Search(context.Background(), boards[i], Limits{MaxDepth: test.depth})
+			nodes += result.Nodes
+		}
+	}
+	b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+}
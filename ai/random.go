@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// randomEngine plays a random valid move, it serves as a baseline for other engines
+type randomEngine struct {
+	random *rand.Rand
+}
+
+// NewRandomEngine creates an engine that plays random moves
+func NewRandomEngine(random *rand.Rand) Engine {
+	return &randomEngine{random: random}
+}
+
+func (r *randomEngine) Search(ctx context.Context, board *game.Board, _ Limits) (SearchResult, error) {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return SearchResult{}, err
+	}
+
+	result := SearchResult{Evaluation: NoWin}
+	if moves := board.GetPossibleMoves(); len(moves) > 0 {
+		result.BestMove = moves[r.random.Intn(len(moves))]
+		result.PV = []game.Move{result.BestMove}
+		result.Nodes = len(moves)
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Limits restricts a search
+type Limits struct {
+	MaxDepth int
+	MoveTime time.Duration // If positive, iterative deepening up to MaxDepth is used until the time is up
+	// OnIteration is called by the goroutine of the search after every completed iteration of iterative deepening,
+	// and after a completed search to a fixed depth, e.g. to report the progress of long searches. It may be nil.
+	// Engines that do not search in iterations do not call it.
+	OnIteration func(Iteration)
+}
+
+// SearchStats counts events during a search
+type SearchStats struct {
+	Nodes       int // Visited nodes of all threads
+	TTHits      int // Lookups that found a cutoff or a best move in the transposition table
+	TTMisses    int // Lookups that found nothing useful in the transposition table
+	TTCutoffs   int // Nodes that were not searched because of a transposition table entry
+	BetaCutoffs int // Nodes whose remaining moves were pruned by alpha-beta
+}
+
+// SearchResult is the outcome of a search
+type SearchResult struct {
+	BestMove   game.Move
+	Evaluation int
+	PV         []game.Move // Principal variation, the expected continuation starting with BestMove
+	Depth      int         // Depth of the deepest completed search
+	Elapsed    time.Duration
+	Iterations []Iteration // Completed iterations of an iterative deepening search
+	// WinIn is the number of plies until the end of a game proven by Evaluation, positive if Player 1 wins and negative
+	// if Player 2 wins. It is 0 if no win is proven or its distance is unknown, e.g. for wins proven by MCTS.
+	WinIn int
+	SearchStats
+}
+
+func (m *minimax) Search(ctx context.Context, board *game.Board, limits Limits) (result SearchResult, err error) {
+	if limits.MoveTime <= 0 {
+		result, err = m.searchDepth(ctx, board, limits.MaxDepth, limits.OnIteration)
+	} else {
+		timedCtx, cancel := context.WithTimeout(ctx, limits.MoveTime)
+		defer cancel()
+		result = m.iterativeDeepening(timedCtx, board, limits.MaxDepth, limits.OnIteration)
+		// Running out of time is the regular end of a timed search, only report cancellation by the caller
+		err = ctx.Err()
+	}
+	result.WinIn = WinIn(result.Evaluation)
+	return result, err
+}
+
+// searchDepth searches the board to a fixed depth and reports the completed search to onIteration, which may be nil
+func (m *minimax) searchDepth(ctx context.Context, board *game.Board, depth int, onIteration func(Iteration)) (SearchResult, error) {
+	if result, found := m.lookupKnownResult(board); found {
+		return result, nil
+	}
+
+	defer m.startSearch(ctx)()
+	start := time.Now()
+
+	stopHelpers := m.startHelpers(board, depth)
+	evaluation, bestMove := m.search(board, depth, -infinity, infinity)
+	m.stats.add(stopHelpers())
+	result := SearchResult{
+		BestMove:    bestMove,
+		Evaluation:  evaluation,
+		PV:          append([]game.Move(nil), m.pv[0]...),
+		Depth:       depth,
+		Elapsed:     time.Since(start),
+		SearchStats: m.stats,
+	}
+
+	if m.aborted {
+		result.Depth = 0
+		if result.BestMove == (game.Move{}) {
+			result.BestMove = m.fallbackMove(board)
+			result.PV = nil
+		}
+		return result, ctx.Err()
+	}
+	if onIteration != nil {
+		onIteration(Iteration{Depth: depth, Evaluation: evaluation, BestMove: bestMove, PV: result.PV,
+			Nodes: result.Nodes, Elapsed: result.Elapsed})
+	}
+	return result, nil
+}
+
+// lookupKnownResult returns the result for positions that are part of the tablebase or the opening book
+func (m *minimax) lookupKnownResult(board *game.Board) (SearchResult, bool) {
+	if result, found := m.probeTablebase(board); found {
+		return result, true
+	}
+	return m.probeOpeningBook(board)
+}
+
+// probeOpeningBook looks up the best move in the opening book
+func (m *minimax) probeOpeningBook(board *game.Board) (result SearchResult, found bool) {
+	if m.openingBook == nil {
+		return result, false
+	}
+	bestMove, entry, found := m.openingBook.Lookup(board)
+	if !found {
+		return result, false
+	}
+	return SearchResult{BestMove: bestMove, Evaluation: entry.Evaluation, PV: []game.Move{bestMove}, Depth: entry.Depth}, true
+}
+
+// probeTablebase looks up the best move in the tablebase and follows the best moves until the end of the game
+func (m *minimax) probeTablebase(board *game.Board) (result SearchResult, found bool) {
+	if m.tablebase == nil {
+		return result, false
+	}
+	start := time.Now()
+	bestMove, outcome, distance, found := m.tablebase.BestMove(board)
+	if !found {
+		return result, false
+	}
+
+	result.BestMove = bestMove
+	result.Depth = distance
+	switch {
+	case outcome == OutcomeDraw:
+		result.Evaluation = NoWin
+	case (outcome == OutcomeWin) == isMaximizingPlayer(board.ActivePlayer):
+		result.Evaluation = WinScore(game.Player1, distance)
+	default:
+		result.Evaluation = WinScore(game.Player2, distance)
+	}
+
+	for move := bestMove; found && len(result.PV) < max(distance, 1); {
+		result.PV = append(result.PV, move)
+		board.MustMakeMove(move)
+		move, _, _, found = m.tablebase.BestMove(board)
+	}
+	for i := len(result.PV) - 1; i >= 0; i-- {
+		board.MustUndoMove(result.PV[i])
+	}
+
+	result.Elapsed = time.Since(start)
+	return result, true
+}
+
+// clearPV starts a new principal variation for the ply
+func (m *minimax) clearPV(ply int) {
+	for len(m.pv) < ply+2 {
+		m.pv = append(m.pv, nil)
+	}
+	m.pv[ply] = m.pv[ply][:0]
+}
+
+// updatePV sets the principal variation of the ply to the move followed by the principal variation of the next ply
+func (m *minimax) updatePV(ply int, move game.Move) {
+	m.pv[ply] = append(append(m.pv[ply][:0], move), m.pv[ply+1]...)
+}
@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// DefaultMaxPositions limits the number of positions the solver enumerates before giving up
+const DefaultMaxPositions = 20_000_000
+
+// unknownDistance marks positions whose value has not been determined yet
+const unknownDistance = -1
+
+// solverGraph is the graph of all positions reachable from a root position.
+// Positions are identified by their canonical hash, so symmetric positions are only stored once.
+type solverGraph struct {
+	keys       []uint64
+	index      map[uint64]int32
+	successors [][]int32
+	terminal   []Outcome // Outcome for the player to move of positions without successors
+}
+
+// Solve computes the exact outcome and distance to the end of the game for every position reachable from the board
+// by retrograde analysis. Positions from which neither player can force a win are draws.
+// Positions with an immediately winning move are not expanded further, so the tablebase only contains
+// the positions that are needed to play perfectly.
+// An error is returned if more than maxPositions positions are reachable.
+func Solve(board *game.Board, maxPositions int) (*Tablebase, error) {
+	graph, err := enumeratePositions(board, maxPositions)
+	if err != nil {
+		return nil, err
+	}
+	tablebase := graph.retrogradeAnalysis()
+	tablebase.rules = board.Rules
+	return tablebase, nil
+}
+
+// enumeratePositions explores all positions reachable from the board in breadth-first order
+func enumeratePositions(board *game.Board, maxPositions int) (*solverGraph, error) {
+	graph := &solverGraph{index: make(map[uint64]int32)}
+	queue := []string{board.Encode()}
+	graph.add(board)
+
+	for next := 0; next < len(queue); next++ {
+		current, err := game.ParsePositionWithRules(queue[next], board.Rules)
+		if err != nil {
+			return nil, err
+		}
+		queue[next] = "" // Release the encoded position, it is not needed anymore
+
+		if winner := current.CheckWin(); winner != game.None {
+			if winner == current.ActivePlayer {
+				graph.terminal[next] = OutcomeWin
+			} else {
+				graph.terminal[next] = OutcomeLoss
+			}
+			continue
+		}
+
+		// A move that wins immediately is always best, so the other moves need not be explored
+		moves := current.GetPossibleMoves()
+		if winningMove, found := findWinningMove(current, moves); found {
+			moves = []game.Move{winningMove}
+		}
+
+		var successors []int32
+		for _, move := range moves {
+			current.MustMakeMove(move)
+			successor, isNew := graph.add(current)
+			if isNew {
+				if len(graph.keys) > maxPositions {
+					return nil, fmt.Errorf("more than %d positions are reachable", maxPositions)
+				}
+				queue = append(queue, current.Encode())
+			}
+			current.MustUndoMove(move)
+
+			// Symmetric moves lead to the same successor
+			if !slices.Contains(successors, successor) {
+				successors = append(successors, successor)
+			}
+		}
+		graph.successors[next] = successors
+	}
+	return graph, nil
+}
+
+// findWinningMove returns a move that completes a line for the active player
+func findWinningMove(board *game.Board, moves []game.Move) (winningMove game.Move, found bool) {
+	for _, move := range moves {
+		board.MustMakeMove(move)
+		found = board.CheckLineWin() == board.ActivePlayer.Opponent()
+		board.MustUndoMove(move)
+		if found {
+			return move, true
+		}
+	}
+	return game.Move{}, false
+}
+
+// add returns the index of the board in the graph and whether it was added as a new position
+func (g *solverGraph) add(board *game.Board) (index int32, isNew bool) {
+	key, _ := board.CanonicalHash()
+	if index, exists := g.index[key]; exists {
+		return index, false
+	}
+	index = int32(len(g.keys))
+	g.index[key] = index
+	g.keys = append(g.keys, key)
+	g.successors = append(g.successors, nil)
+	g.terminal = append(g.terminal, OutcomeDraw)
+	return index, true
+}
+
+// retrogradeAnalysis propagates the outcomes of the terminal positions backwards through the graph.
+// Positions are resolved in order of increasing distance, so a won position gets the shortest distance to a win
+// and a lost position the longest distance to a loss.
+func (g *solverGraph) retrogradeAnalysis() *Tablebase {
+	count := len(g.keys)
+	outcomes := make([]Outcome, count)
+	distances := make([]int, count)
+	unresolvedSuccessors := make([]int, count)
+	predecessors := make([][]int32, count)
+
+	var queue []int32
+	for i := range count {
+		distances[i] = unknownDistance
+		outcomes[i] = OutcomeDraw
+		unresolvedSuccessors[i] = len(g.successors[i])
+		for _, successor := range g.successors[i] {
+			predecessors[successor] = append(predecessors[successor], int32(i))
+		}
+		if len(g.successors[i]) == 0 {
+			outcomes[i] = g.terminal[i]
+			distances[i] = 0
+			queue = append(queue, int32(i))
+		}
+	}
+
+	for next := 0; next < len(queue); next++ {
+		position := queue[next]
+		for _, predecessor := range predecessors[position] {
+			if distances[predecessor] != unknownDistance {
+				continue
+			}
+
+			if outcomes[position] == OutcomeLoss {
+				// The player to move in the predecessor can move into a lost position for the opponent
+				outcomes[predecessor] = OutcomeWin
+				distances[predecessor] = distances[position] + 1
+				queue = append(queue, predecessor)
+			} else if unresolvedSuccessors[predecessor]--; unresolvedSuccessors[predecessor] == 0 {
+				// All moves of the predecessor lead to positions won by the opponent
+				outcomes[predecessor] = OutcomeLoss
+				distances[predecessor] = distances[position] + 1
+				queue = append(queue, predecessor)
+			}
+		}
+	}
+
+	tablebase := &Tablebase{}
+	for i, key := range g.keys {
+		tablebase.add(key, outcomes[i], max(distances[i], 0))
+	}
+	tablebase.sort()
+	return tablebase
+}
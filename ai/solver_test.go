@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+// Positions without pieces in reserve have only a few reachable positions
+var solverPositions = []string{
+	"mL,-,-/s,l,mL/s,SM,SMl 1 000/000",
+	"s,-,-/ML,l,SMl/Sm,-,mL 1 000/100",
+}
+
+func TestSolveMatchesMinimax(t *testing.T) {
+	for _, position := range solverPositions {
+		board, err := game.ParsePosition(position)
+		assert.NoError(t, err)
+
+		tablebase, err := Solve(board, DefaultMaxPositions)
+		assert.NoError(t, err)
+		outcome, distance, found := tablebase.Probe(board)
+		assert.True(t, found, "root position must be solved")
+		assert.Equal(t, OutcomeLoss, outcome, "position %q must be lost for Player 1", position)
+		assert.Equal(t, 4, distance, "position %q must be lost in 4 plies", position)
+
+		// Minimax must not find the loss with less depth than the distance
+		minimax := NewMinimax()
+		assert.Equal(t, game.None, minimax.CalculateWinner(board, distance-1))
+		assert.Equal(t, game.Player2, minimax.CalculateWinner(board, distance))
+
+		// Every move of Player 1 leads to a win of Player 2 in at most 3 plies
+		for _, move := range board.GetPossibleMoves() {
+			board.MustMakeMove(move)
+			outcome, successorDistance, found := tablebase.Probe(board)
+			board.MustUndoMove(move)
+			assert.True(t, found, "successors of lost positions must be solved")
+			assert.Equal(t, OutcomeWin, outcome)
+			assert.LessOrEqual(t, successorDistance, 3)
+		}
+	}
+}
+
+func TestSolveTooManyPositions(t *testing.T) {
+	_, err := Solve(game.NewBoard(), 1000)
+	assert.Error(t, err, "solving the start position must exceed 1000 positions")
+}
+
+func TestTablebaseRoundTrip(t *testing.T) {
+	board, _ := game.ParsePosition(solverPositions[0])
+	tablebase, err := Solve(board, DefaultMaxPositions)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, tablebase.Write(&buf))
+	assert.Equal(t, 20+len("standard")+10*tablebase.Len(), buf.Len(), "every position must take 10 bytes")
+	written := bytes.Clone(buf.Bytes())
+
+	read, err := ReadTablebase(&buf, game.DefaultRules)
+	assert.NoError(t, err)
+	assert.Equal(t, tablebase, read, "read tablebase must be equal to the written tablebase")
+
+	_, err = ReadTablebase(bytes.NewReader(written), game.GobbletRules)
+	assert.ErrorContains(t, err, "is for the rules standard", "tablebases of other rules must be rejected")
+
+	_, err = ReadTablebase(bytes.NewReader([]byte("not a tablebase")), game.DefaultRules)
+	assert.Error(t, err)
+}
+
+func TestMinimaxWithTablebase(t *testing.T) {
+	board, _ := game.ParsePosition(solverPositions[1])
+	tablebase, err := Solve(board, DefaultMaxPositions)
+	assert.NoError(t, err)
+
+	// Let Player 1 make any move, Player 2 must find the fastest win from the tablebase
+	board.MustMakeMove(board.GetPossibleMoves()[0])
+	_, distance, _ := tablebase.Probe(board)
+	result, err := NewMinimax(WithTablebase(tablebase)).Search(context.Background(), board, Limits{MaxDepth: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, WinScore(game.Player2, distance), result.Evaluation)
+	assert.Equal(t, distance, len(result.PV), "principal variation must lead to the end of the game")
+
+	for _, move := range result.PV {
+		board.MustMakeMove(move)
+	}
+	assert.Equal(t, game.Player2, board.CheckWin(), "principal variation must end with the win of Player 2")
+}
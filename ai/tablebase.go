@@ -0,0 +1,231 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Outcome is the game-theoretic value of a position for the player to move
+type Outcome uint8
+
+const (
+	OutcomeDraw Outcome = iota
+	OutcomeWin
+	OutcomeLoss
+)
+
+const (
+	tablebaseMagic   = "GGTB"
+	tablebaseVersion = 3
+	distanceBits     = 14
+	maxDistance      = 1<<distanceBits - 1
+)
+
+// Tablebase stores the outcome and the distance to the end of the game of solved positions, keyed by canonical hash.
+// On disk, the header names the rules of the positions, and every position takes 10 bytes: the 8 byte key followed by
+// 2 bits outcome and 14 bits distance.
+type Tablebase struct {
+	rules  game.Rules
+	keys   []uint64
+	values []uint16
+}
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeWin:
+		return "Win"
+	case OutcomeLoss:
+		return "Loss"
+	default:
+		return "Draw"
+	}
+}
+
+// Len returns the number of positions in the tablebase
+func (t *Tablebase) Len() int {
+	return len(t.keys)
+}
+
+// Rules returns the rules of the solved positions
+func (t *Tablebase) Rules() game.Rules {
+	return t.rules
+}
+
+// Probe returns the outcome for the player to move and the number of plies until the end of the game.
+// Draws have distance 0. found is false if the position is not part of the tablebase, e.g. because it is played
+// with other rules.
+func (t *Tablebase) Probe(board *game.Board) (outcome Outcome, distance int, found bool) {
+	if board.Rules != t.rules {
+		return OutcomeDraw, 0, false
+	}
+	key, _ := board.CanonicalHash()
+	i, found := slices.BinarySearch(t.keys, key)
+	if !found {
+		return OutcomeDraw, 0, false
+	}
+	value := t.values[i]
+	return Outcome(value >> distanceBits), int(value & maxDistance), true
+}
+
+// BestMove returns the move that wins fastest, keeps a draw, or delays the loss longest.
+// Moves leading to positions that are not part of the tablebase are skipped.
+// found is false if the position is not part of the tablebase or the game is over.
+func (t *Tablebase) BestMove(board *game.Board) (bestMove game.Move, outcome Outcome, distance int, found bool) {
+	outcome, distance, found = t.Probe(board)
+	if !found || board.Outcome().IsOver() {
+		return game.Move{}, outcome, distance, false
+	}
+
+	bestScore := 0
+	for _, move := range board.GetPossibleMoves() {
+		board.MustMakeMove(move)
+		successorOutcome, successorDistance, successorFound := t.Probe(board)
+		board.MustUndoMove(move)
+		if !successorFound {
+			continue
+		}
+
+		// Positions lost for the opponent are best, among them the ones closest to the end
+		score := 0
+		switch successorOutcome {
+		case OutcomeLoss:
+			score = 2*maxDistance - successorDistance
+		case OutcomeDraw:
+			score = maxDistance
+		case OutcomeWin:
+			score = successorDistance
+		}
+		if bestMove == (game.Move{}) || score > bestScore {
+			bestMove, bestScore = move, score
+		}
+	}
+	return bestMove, outcome, distance, bestMove != (game.Move{})
+}
+
+func (t *Tablebase) add(key uint64, outcome Outcome, distance int) {
+	t.keys = append(t.keys, key)
+	t.values = append(t.values, uint16(outcome)<<distanceBits|uint16(min(distance, maxDistance)))
+}
+
+func (t *Tablebase) sort() {
+	sort.Sort(tablebaseByKey{t})
+}
+
+type tablebaseByKey struct{ *Tablebase }
+
+func (t tablebaseByKey) Len() int           { return len(t.keys) }
+func (t tablebaseByKey) Less(i, j int) bool { return t.keys[i] < t.keys[j] }
+func (t tablebaseByKey) Swap(i, j int) {
+	t.keys[i], t.keys[j] = t.keys[j], t.keys[i]
+	t.values[i], t.values[j] = t.values[j], t.values[i]
+}
+
+// Write writes the tablebase in its binary format
+func (t *Tablebase) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	rules := t.rules.String()
+	header := make([]byte, 0, 20+len(rules))
+	header = append(header, tablebaseMagic...)
+	header = binary.LittleEndian.AppendUint32(header, tablebaseVersion)
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(t.keys)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(rules)))
+	header = append(header, rules...)
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+
+	entry := make([]byte, 10)
+	for i, key := range t.keys {
+		binary.LittleEndian.PutUint64(entry, key)
+		binary.LittleEndian.PutUint16(entry[8:], t.values[i])
+		if _, err := writer.Write(entry); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadTablebase reads a tablebase written by Tablebase.Write, which must have been solved for the rules
+func ReadTablebase(r io.Reader, rules game.Rules) (*Tablebase, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("reading tablebase header: %w", err)
+	}
+	if string(header[:4]) != tablebaseMagic {
+		return nil, errors.New("not a tablebase file")
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != tablebaseVersion {
+		return nil, fmt.Errorf("unsupported tablebase version %d", version)
+	}
+
+	rulesName := make([]byte, min(binary.LittleEndian.Uint32(header[16:]), maxRulesLength))
+	if _, err := io.ReadFull(reader, rulesName); err != nil {
+		return nil, fmt.Errorf("reading tablebase header: %w", err)
+	}
+	if err := checkRules(string(rulesName), rules); err != nil {
+		return nil, fmt.Errorf("tablebase %w", err)
+	}
+
+	count := binary.LittleEndian.Uint64(header[8:])
+	capacity := min(count, 1<<20) // Do not trust the header with the initial allocation
+	t := &Tablebase{rules: rules, keys: make([]uint64, 0, capacity), values: make([]uint16, 0, capacity)}
+	entry := make([]byte, 10)
+	for range count {
+		if _, err := io.ReadFull(reader, entry); err != nil {
+			return nil, fmt.Errorf("reading tablebase entry: %w", err)
+		}
+		t.keys = append(t.keys, binary.LittleEndian.Uint64(entry))
+		t.values = append(t.values, binary.LittleEndian.Uint16(entry[8:]))
+	}
+	if !slices.IsSorted(t.keys) {
+		return nil, errors.New("tablebase entries are not sorted")
+	}
+	return t, nil
+}
+
+// SaveTablebase writes the tablebase to the file at path
+func SaveTablebase(path string, t *Tablebase) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadTablebase reads the tablebase of the rules from the file at path
+func LoadTablebase(path string, rules game.Rules) (*Tablebase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadTablebase(file, rules)
+}
+
+// maxRulesLength limits the name of the rules read from a file, longer names are not written by Rules.String
+const maxRulesLength = 256
+
+// checkRules returns an error if the name of the rules read from a file does not describe the expected rules
+func checkRules(name string, expected game.Rules) error {
+	rules, err := game.ParseRules(name)
+	if err != nil {
+		return fmt.Errorf("has invalid rules %q: %w", name, err)
+	}
+	if rules != expected {
+		return fmt.Errorf("is for the rules %v, not %v", rules, expected)
+	}
+	return nil
+}
@@ -1,6 +1,12 @@
 package ai
 
-import "gibhub.com/bef1993/gobblet-gobblers/game"
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
 
 type BoundType int
 
@@ -10,6 +16,12 @@ const (
 	UpperBound
 )
 
+// DefaultTTSize is the memory of a transposition table in MB if no other size is configured
+const DefaultTTSize = 16
+
+// MaxTTSize is the largest memory of a transposition table in MB, larger sizes are reduced to it
+const MaxTTSize = 4096
+
 type TTEntry struct {
 	Evaluation int
 	Depth      int
@@ -17,25 +29,65 @@ type TTEntry struct {
 	BoundType  BoundType
 }
 
+// TranspositionTable caches search results by position hash.
+// LookupHash also returns the stored best move if the entry can not be used for a cutoff, so it can be searched first.
+// The positions of the returned moves only carry their row and column, Board.TransformMove maps them onto a board.
+// Implementations must be safe for concurrent use, so multiple searches can share a table.
 type TranspositionTable interface {
 	LookupHash(hash uint64, depth, alpha, beta int) (found bool, evaluation int, bestMove game.Move)
 	StoreHash(hash uint64, evaluation, depth int, entryType BoundType, bestMove game.Move)
+	// NewSearch starts a new search, so entries of previous searches are replaced first
+	NewSearch()
+	// Clear removes all entries. It must not be called while a search uses the table.
+	Clear()
 }
 
+// transpositionTable is a fixed-size hash table of buckets with two entries. The first entry of a bucket keeps the
+// deepest result of the current search, the second entry is always replaced by results that do not fit into the first.
+//
+// Entries are read and written without locks: every entry consists of two words, the packed data and the hash xor the
+// data. An entry whose words were written by different goroutines at the same time does not match its hash and is ignored.
 type transpositionTable struct {
-	entries map[uint64]TTEntry
+	buckets    []ttBucket
+	mask       uint64 // Number of buckets - 1, the number of buckets is a power of two
+	generation atomic.Uint32
+}
+
+type ttBucket [2]ttSlot
+
+type ttSlot struct {
+	key  atomic.Uint64 // Hash xor data
+	data atomic.Uint64
 }
 
-func NewTranspositionTable() TranspositionTable {
+const ttBucketBytes = 32
+
+// NewTranspositionTable creates a transposition table that uses at most sizeMB megabytes of memory
+func NewTranspositionTable(sizeMB int) TranspositionTable {
+	buckets := uint64(min(max(sizeMB, 1), MaxTTSize)) << 20 / ttBucketBytes
+	buckets = 1 << (bits.Len64(buckets) - 1)
 	return &transpositionTable{
-		entries: make(map[uint64]TTEntry),
+		buckets: make([]ttBucket, buckets),
+		mask:    buckets - 1,
 	}
 }
 
+func (t *transpositionTable) NewSearch() {
+	t.generation.Add(1)
+}
+
+func (t *transpositionTable) Clear() {
+	clear(t.buckets)
+	t.generation.Store(0)
+}
+
 func (t *transpositionTable) LookupHash(hash uint64, depth, alpha, beta int) (found bool, evaluation int, bestMove game.Move) {
-	entry, exists := t.entries[hash]
-	if !exists || entry.Depth < depth {
-		return false, NoWin, game.Move{} // Not found or outdated
+	entry, exists := t.probe(hash)
+	if !exists {
+		return false, NoWin, game.Move{}
+	}
+	if entry.Depth < depth {
+		return false, NoWin, entry.BestMove // Outdated, but the best move is still useful for move ordering
 	}
 
 	// Use stored value if it helps pruning
@@ -47,14 +99,132 @@ func (t *transpositionTable) LookupHash(hash uint64, depth, alpha, beta int) (fo
 		return true, entry.Evaluation, entry.BestMove
 	}
 
-	return false, NoWin, game.Move{}
+	return false, NoWin, entry.BestMove
+}
+
+// probe returns the deepest entry of the hash
+func (t *transpositionTable) probe(hash uint64) (entry TTEntry, found bool) {
+	bucket := &t.buckets[hash&t.mask]
+	for i := range bucket {
+		data, ok := bucket[i].load(hash)
+		if !ok {
+			continue
+		}
+		if candidate := data.entry(); !found || candidate.Depth > entry.Depth {
+			entry, found = candidate, true
+		}
+	}
+	return entry, found
 }
 
 func (t *transpositionTable) StoreHash(hash uint64, evaluation, depth int, entryType BoundType, bestMove game.Move) {
-	existing, exists := t.entries[hash]
+	generation := t.generation.Load()
+	bucket := &t.buckets[hash&t.mask]
+	deepest, always := &bucket[0], &bucket[1]
+
+	// Keep the best move of the position if the new result has none
+	if bestMove == (game.Move{}) {
+		if entry, found := t.probe(hash); found {
+			bestMove = entry.BestMove
+		}
+	}
+	data := packEntry(evaluation, depth, entryType, bestMove, generation)
+
+	// Deeper results of the current search are kept, results of previous searches are replaced
+	if existing := ttData(deepest.data.Load()); existing != 0 && existing.generation() == generation%ttGenerations &&
+		existing.depth() > data.depth() {
+		always.store(hash, data)
+		return
+	}
+	deepest.store(hash, data)
+}
+
+// load returns the data of the slot if it holds an entry of the hash
+func (s *ttSlot) load(hash uint64) (ttData, bool) {
+	key, data := s.key.Load(), s.data.Load()
+	return ttData(data), data != 0 && key^data == hash
+}
+
+func (s *ttSlot) store(hash uint64, data ttData) {
+	s.key.Store(hash ^ uint64(data))
+	s.data.Store(uint64(data))
+}
+
+// ttData packs an entry into 64 bits: the evaluation (32 bits), the best move (16 bits), the depth (8 bits),
+// the bound type + 1 (2 bits, so the data of used entries is never 0) and the generation (6 bits)
+type ttData uint64
+
+const (
+	ttMoveShift       = 32
+	ttDepthShift      = 48
+	ttBoundShift      = 56
+	ttGenerationShift = 58
+	ttGenerations     = 1 << 6
+	ttMaxDepth        = 1<<8 - 1
+)
+
+func packEntry(evaluation, depth int, entryType BoundType, bestMove game.Move, generation uint32) ttData {
+	evaluation = min(max(evaluation, math.MinInt32), math.MaxInt32)
+	depth = min(max(depth, 0), ttMaxDepth) // Deeper entries are stored as ttMaxDepth, which is still a valid lower limit
+	return ttData(uint32(int32(evaluation))) |
+		ttData(packMove(bestMove))<<ttMoveShift |
+		ttData(depth)<<ttDepthShift |
+		ttData(entryType+1)<<ttBoundShift |
+		ttData(generation%ttGenerations)<<ttGenerationShift
+}
+
+func (d ttData) depth() int {
+	return int(d >> ttDepthShift & ttMaxDepth)
+}
+
+func (d ttData) generation() uint32 {
+	return uint32(d >> ttGenerationShift)
+}
+
+func (d ttData) entry() TTEntry {
+	return TTEntry{
+		Evaluation: int(int32(uint32(d))),
+		Depth:      d.depth(),
+		BestMove:   unpackMove(uint16(d >> ttMoveShift)),
+		BoundType:  BoundType(d>>ttBoundShift&3) - 1,
+	}
+}
+
+// A packed move consists of the indexes of the destination and the origin (5 bits each, noSquare if not set),
+// the owner and the size of a placed piece (2 bits each)
+const noSquare = 1<<5 - 1
+
+// squares are the positions of packed moves, which are not part of any board
+var squares = func() (squares [game.MaxBoardSize * game.MaxBoardSize]game.Position) {
+	for i := range squares {
+		squares[i] = game.Position{Row: i / game.MaxBoardSize, Col: i % game.MaxBoardSize}
+	}
+	return squares
+}()
+
+func packMove(move game.Move) uint16 {
+	return uint16(squareIndex(move.To)) | uint16(squareIndex(move.From))<<5 |
+		uint16(move.Piece.Owner)<<10 | uint16(move.Piece.Size)<<12
+}
+
+func unpackMove(packed uint16) game.Move {
+	return game.Move{
+		Piece: game.Piece{Owner: game.Player(packed >> 10 & 3), Size: game.Size(packed >> 12 & 3)},
+		From:  square(int(packed >> 5 & noSquare)),
+		To:    square(int(packed & noSquare)),
+	}
+}
+
+func squareIndex(p *game.Position) int {
+	if p == nil {
+		return noSquare
+	}
+	return p.Row*game.MaxBoardSize + p.Col
+}
 
-	// Only replace if the new depth is greater, or it's a new entry
-	if !exists || depth > existing.Depth {
-		t.entries[hash] = TTEntry{Evaluation: evaluation, Depth: depth, BoundType: entryType, BestMove: bestMove}
+func square(index int) *game.Position {
+	if index == noSquare {
+		return nil
 	}
+	return &squares[index]
 }
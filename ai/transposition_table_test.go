@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"sync"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranspositionTableSize(t *testing.T) {
+	table := NewTranspositionTable(1).(*transpositionTable)
+	assert.Len(t, table.buckets, 1<<20/ttBucketBytes)
+
+	table = NewTranspositionTable(3).(*transpositionTable)
+	assert.Len(t, table.buckets, 2<<20/ttBucketBytes, "the number of buckets is rounded down to a power of two")
+}
+
+func TestTranspositionTableLookup(t *testing.T) {
+	table := NewTranspositionTable(1)
+	board := game.NewBoard()
+	move := game.NewMove(game.Player1, board.Get(1, 1), game.Large)
+
+	found, _, bestMove := table.LookupHash(42, 0, -10, 10)
+	assert.False(t, found)
+	assert.Equal(t, game.Move{}, bestMove)
+
+	table.StoreHash(42, 7, 3, ExactBound, move)
+	found, evaluation, bestMove := table.LookupHash(42, 3, -10, 10)
+	assert.True(t, found)
+	assert.Equal(t, 7, evaluation)
+	assert.Equal(t, move, board.TransformMove(bestMove, game.Identity))
+
+	found, _, bestMove = table.LookupHash(42, 4, -10, 10)
+	assert.False(t, found, "shallower entries can not be used")
+	assert.Equal(t, move, board.TransformMove(bestMove, game.Identity), "the best move is returned for move ordering")
+
+	table.StoreHash(43, 20, 3, LowerBound, game.Move{})
+	found, _, _ = table.LookupHash(43, 3, -10, 10)
+	assert.True(t, found, "a lower bound above beta causes a cutoff")
+	found, _, _ = table.LookupHash(43, 3, -10, 30)
+	assert.False(t, found)
+
+	found, _, _ = table.LookupHash(42^1<<40, 0, -10, 10)
+	assert.False(t, found, "entries of other hashes in the same bucket are not returned")
+}
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	table := NewTranspositionTable(1)
+	buckets := uint64(len(table.(*transpositionTable).buckets))
+	board := game.NewBoard()
+	move := game.NewMoveExisting(board.Get(0, 0), board.Get(2, 1))
+
+	table.StoreHash(1, 100, 8, ExactBound, move)
+	table.StoreHash(1+buckets, 50, 2, ExactBound, game.Move{})
+	found, evaluation, _ := table.LookupHash(1, 8, -1000, 1000)
+	assert.True(t, found, "the deeper entry of the current search is kept")
+	assert.Equal(t, 100, evaluation)
+	found, _, _ = table.LookupHash(1+buckets, 2, -1000, 1000)
+	assert.True(t, found, "the shallower entry is stored in the second entry of the bucket")
+
+	table.StoreHash(1, 30, 1, ExactBound, game.Move{})
+	found, evaluation, bestMove := table.LookupHash(1, 1, -1000, 1000)
+	assert.True(t, found)
+	assert.Equal(t, 100, evaluation, "the deepest entry of a position is used")
+	assert.Equal(t, move, board.TransformMove(bestMove, game.Identity))
+
+	table.NewSearch()
+	table.StoreHash(1+2*buckets, 10, 1, ExactBound, game.Move{})
+	found, _, _ = table.LookupHash(1, 8, -1000, 1000)
+	assert.False(t, found, "deep entries of previous searches are replaced")
+	found, _, _ = table.LookupHash(1+2*buckets, 1, -1000, 1000)
+	assert.True(t, found)
+}
+
+func TestPackEntry(t *testing.T) {
+	board := game.NewBoardWithRules(game.Rules{BoardSize: 5, WinLength: 4, PieceSizes: 4, PiecesPerSize: 2})
+	moves := []game.Move{
+		{},
+		game.NewMove(game.Player2, board.Get(4, 4), game.ExtraLarge),
+		game.NewMoveExisting(board.Get(4, 3), board.Get(0, 0)),
+	}
+	for _, move := range moves {
+		for _, entry := range []TTEntry{
+			{Evaluation: Player2Win - 12, Depth: 12, BoundType: UpperBound, BestMove: move},
+			{Evaluation: 1 << 40, Depth: 1000, BoundType: LowerBound, BestMove: move},
+		} {
+			unpacked := packEntry(entry.Evaluation, entry.Depth, entry.BoundType, entry.BestMove, 77).entry()
+			unpacked.BestMove = board.TransformMove(unpacked.BestMove, game.Identity)
+			if entry.Depth > ttMaxDepth {
+				entry.Depth, entry.Evaluation = ttMaxDepth, 1<<31-1
+			}
+			assert.Equal(t, entry, unpacked)
+		}
+	}
+}
+
+func TestTranspositionTableConcurrent(t *testing.T) {
+	table := NewTranspositionTable(1)
+	board := game.NewBoard()
+	move := game.NewMove(game.Player1, board.Get(0, 2), game.Small)
+
+	var wg sync.WaitGroup
+	for worker := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10000 {
+				hash := uint64(i % 100)
+				table.StoreHash(hash, int(hash), worker, ExactBound, move)
+				if found, evaluation, bestMove := table.LookupHash(hash, 0, -1000, 1000); found {
+					assert.Equal(t, int(hash), evaluation)
+					assert.Equal(t, move, board.TransformMove(bestMove, game.Identity))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -1,98 +1,216 @@
 package cli
 
 import (
-	"errors"
+	"bufio"
+	"context"
 	"fmt"
-	"regexp"
+	"io"
+	"os"
 	"strings"
-	"unicode"
+	"time"
 
 	"gibhub.com/bef1993/gobblet-gobblers/ai"
 	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"gibhub.com/bef1993/gobblet-gobblers/tournament"
 )
 
-const RegexMovePattern = "^[abcABC][1-3] ([sS]|[mM]|[lL]|[abcABC][1-3])$"
+const (
+	undoCommand     = "undo"
+	redoCommand     = "redo"
+	takebackCommand = "takeback"
+)
+
+var input = bufio.NewReader(os.Stdin)
+
+// Participant is one side of a game: a human entering moves on the terminal, or an engine if Engine is set
+type Participant struct {
+	Name   string
+	Engine ai.Engine
+	Limits ai.Limits // Limits of every search of the engine
+}
+
+// Human creates a participant that enters moves on the terminal
+func Human() Participant {
+	return Participant{Name: "Human"}
+}
+
+// AI creates a participant whose moves are chosen by the engine within the given limits
+func AI(name string, engine ai.Engine, limits ai.Limits) Participant {
+	return Participant{Name: name, Engine: engine, Limits: limits}
+}
+
+func (p Participant) isHuman() bool {
+	return p.Engine == nil
+}
+
+// recordName returns the name of the participant together with the limits of its engine
+func (p Participant) recordName() string {
+	if p.isHuman() {
+		return p.Name
+	}
+	if p.Limits.MoveTime > 0 {
+		return fmt.Sprintf("%s (%v per move)", p.Name, p.Limits.MoveTime)
+	}
+	return fmt.Sprintf("%s (depth %d)", p.Name, p.Limits.MaxDepth)
+}
 
-func PlayGame(human game.Player, maxDepth int) {
-	board := game.NewBoard()
-	minimax := ai.NewMinimax()
-	var winner game.Player
+// PlayGame plays a game between the participants and returns the record of the game.
+// The game is played with the given rules and drawn by threefold repetition or when no one has won after maxPlies plies (0 for no limit).
+func PlayGame(player1, player2 Participant, rules game.Rules, maxPlies int) *game.GameRecord {
+	board := game.NewBoardWithRules(rules)
+	board.DrawRules.MaxPlies = maxPlies
+	record := game.NewGameRecord(board, player1.recordName(), player2.recordName())
+	history := &moveHistory{}
+	hasHuman := player1.isHuman() || player2.isHuman()
+	var outcome game.Outcome
 
 	printBoard(board)
-	fmt.Println("Placing piece: b2 S   -   Moving piece: b1 a0")
+	if hasHuman {
+		fmt.Println("Placing piece: b2 S   -   Moving piece: b1 a3")
+		fmt.Println("Commands: undo, redo, takeback (undo the last two moves)")
+	}
 
 	for {
+		participant := player1
+		if board.ActivePlayer == game.Player2 {
+			participant = player2
+		}
 
-		if board.ActivePlayer == human {
+		if participant.isHuman() {
+			fmt.Printf("%v to move\n", board.ActivePlayer)
 			printAvailablePieces(board)
-			makeHumanMove(board)
+			if err := makeHumanMove(board, history); err != nil {
+				fmt.Println(err)
+				break
+			}
 		} else {
-			fmt.Println("Waiting for AI to make move ...")
-			makeAIMove(board, minimax, maxDepth)
+			fmt.Printf("Waiting for %v (%v) to make move ...\n", participant.Name, board.ActivePlayer)
+			history.push(makeAIMove(board, participant.Engine, participant.Limits))
 		}
 		printBoard(board)
 
-		winner = board.CheckWin()
-		if winner != game.None {
+		outcome = board.Outcome()
+		if outcome.IsOver() {
 			break
 		}
 	}
 
-	fmt.Println("Winner:", winner)
+	for _, move := range history.played {
+		record.AddMove(move)
+	}
+	if outcome == game.Drawn {
+		fmt.Printf("Draw after %v plies\n", len(history.played))
+	} else {
+		fmt.Println("Winner:", outcome.Winner())
+	}
+	record.Finish(outcome)
 
-	// Wait for a single key press before exiting
-	_, _ = fmt.Scanln()
+	if hasHuman {
+		// Wait for a single key press before exiting
+		_, _ = readLine()
+	} else {
+		fmt.Println()
+		_ = game.WriteRecord(os.Stdout, record)
+	}
+	return record
 }
 
-func makeHumanMove(board *game.Board) {
-	var move game.Move
+// makeHumanMove reads input until the human made a valid move. Commands change the board without ending the turn,
+// so an engine does not reply to an undone move and the undone moves can still be redone.
+func makeHumanMove(board *game.Board, history *moveHistory) error {
+	fmt.Println("Enter your move:")
 	for {
-		move = getHumanMove(board)
-		err := board.MakeMove(move)
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(line) {
+		case undoCommand:
+			err = history.undo(board)
+		case redoCommand:
+			err = history.redo(board)
+		case takebackCommand:
+			err = history.takeback(board)
+		default:
+			var move game.Move
+			move, err = game.ParseMove(strings.Join(strings.Fields(line), " "), board)
+			if err != nil {
+				fmt.Println("Invalid input. Please enter move again:")
+				continue
+			}
+			if err = board.MakeMove(move); err == nil {
+				history.push(move)
+				return nil
+			}
+		}
+
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
-		break
+		printBoard(board)
+		fmt.Printf("%v to move\n", board.ActivePlayer)
+		printAvailablePieces(board)
+		fmt.Println("Enter your move:")
 	}
 }
 
-func getHumanMove(board *game.Board) (move game.Move) {
+func makeAIMove(board *game.Board, engine ai.Engine, limits ai.Limits) game.Move {
+	result, _ := engine.Search(context.Background(), board, limits)
+	printSearchResult(result)
+	fmt.Printf("AI Move: %v\n", game.MoveString(result.BestMove))
+	board.MustMakeMove(result.BestMove)
+	return result.BestMove
+}
 
-	fmt.Println("Enter your move:")
-	for {
-		var input1, input2 string
-		n, err := fmt.Scan(&input1, &input2)
-		if err != nil || n != 2 {
-			fmt.Println("Invalid input")
-			continue
-		}
-		move, err = ParseMove(strings.Join([]string{input1, input2}, " "), board)
-		if err != nil {
-			fmt.Println("Invalid input. Please enter move again:")
-			continue
-		}
-		return move
+func printSearchResult(result ai.SearchResult) {
+	for _, iteration := range result.Iterations {
+		fmt.Printf("Depth %v: %v (Evaluation: %v, %v)\n", iteration.Depth, game.MoveString(iteration.BestMove),
+			iteration.Evaluation, iteration.Elapsed.Round(time.Millisecond))
+	}
+	fmt.Printf("Evaluation: %v, Depth: %v, Nodes: %v, Time: %v\n",
+		result.Evaluation, result.Depth, result.Nodes, result.Elapsed.Round(time.Millisecond))
+	fmt.Printf("TT hits: %v, TT misses: %v, TT cutoffs: %v, Beta cutoffs: %v\n",
+		result.TTHits, result.TTMisses, result.TTCutoffs, result.BetaCutoffs)
+	fmt.Printf("PV: %v\n", pvString(result.PV))
+	if announcement := forcedWinString(result.Evaluation); announcement != "" {
+		fmt.Println(announcement)
 	}
+}
 
+// forcedWinString announces the winner of an evaluation that proves a win, or returns an empty string
+func forcedWinString(evaluation int) string {
+	winner, plies := ai.ForcedWin(evaluation)
+	switch {
+	case winner == game.None:
+		return ""
+	case plies < 0:
+		return fmt.Sprintf("%v has a forced win", winner)
+	case plies == 1:
+		return fmt.Sprintf("%v wins with the next move", winner)
+	default:
+		return fmt.Sprintf("%v wins in %d plies", winner, plies)
+	}
 }
 
-func makeAIMove(board *game.Board, minimax ai.Minimax, maxDepth int) {
-	move := minimax.GetBestMove(board, maxDepth)
-	fmt.Printf("AI Move: %v\n", MoveString(move))
-	board.MustMakeMove(move)
+func pvString(pv []game.Move) string {
+	moves := make([]string, len(pv))
+	for i, move := range pv {
+		moves[i] = game.MoveString(move)
+	}
+	return strings.Join(moves, ", ")
 }
 
 func DetermineHumanPlayer() (game.Player, error) {
 	for {
-		var player int
-		_, err := fmt.Scanln(&player)
+		line, err := readLine()
 		if err != nil {
 			return game.None, err
 		}
-		if player == 1 {
+		if line == "1" {
 			return game.Player1, nil
-		} else if player == 2 {
+		} else if line == "2" {
 			return game.Player2, nil
 		} else {
 			fmt.Println("Type '1' or '2'")
@@ -101,12 +219,27 @@ func DetermineHumanPlayer() (game.Player, error) {
 	}
 }
 
+// readLine reads the next line from stdin without the trailing line break.
+func readLine() (string, error) {
+	line, err := input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
 func printBoard(board *game.Board) {
-	fmt.Println("  a   b   c ")
-	fmt.Println(" ───────────")
-	for row := 0; row < 3; row++ {
+	size := board.Rules.BoardSize
+	border := " " + strings.Repeat("─", 4*size-1)
+	fmt.Print(" ")
+	for col := 0; col < size; col++ {
+		fmt.Printf(" %c  ", 'a'+col)
+	}
+	fmt.Println()
+	fmt.Println(border)
+	for row := 0; row < size; row++ {
 		fmt.Print(row+1, "| ")
-		for col := 0; col < 3; col++ {
+		for col := 0; col < size; col++ {
 			topPiece := board.Get(row, col).TopPiece()
 			if topPiece == nil {
 				fmt.Print(".")
@@ -117,114 +250,133 @@ func printBoard(board *game.Board) {
 		}
 		fmt.Println()
 	}
-	fmt.Println(" ───────────")
+	fmt.Println(border)
 }
 
 func printAvailablePieces(board *game.Board) {
-	activePlayer := board.ActivePlayer
-	fmt.Printf("Available pieces: %v Small, %v Medium, %v Large\n",
-		board.RemainingPieces[activePlayer][game.Small],
-		board.RemainingPieces[activePlayer][game.Medium],
-		board.RemainingPieces[activePlayer][game.Large])
+	sizeNames := []string{"Small", "Medium", "Large", "Extra Large"}
+	if board.Rules.ExternalStacks {
+		var stacks []string
+		for _, height := range board.StackHeights(board.ActivePlayer) {
+			if height > 0 {
+				stacks = append(stacks, sizeNames[height-1])
+			}
+		}
+		fmt.Printf("Top pieces of your stacks: %v\n", strings.Join(stacks, ", "))
+		return
+	}
 
+	var available []string
+	for size, count := range board.RemainingPieces[board.ActivePlayer] {
+		available = append(available, fmt.Sprintf("%v %v", count, sizeNames[size]))
+	}
+	fmt.Printf("Available pieces: %v\n", strings.Join(available, ", "))
 }
 
-func ParseMove(input string, board *game.Board) (game.Move, error) {
-	matched, err := regexp.Match(RegexMovePattern, []byte(input))
-	if err != nil {
-		panic("invalid regex pattern")
-	}
-	if !matched {
-		return game.Move{}, errors.New("invalid move input")
+// SolvePosition solves the encoded position (the start position of the rules if empty), writes the tablebase to the file at path and prints the outcome
+func SolvePosition(position string, rules game.Rules, maxPositions int, path string) error {
+	board := game.NewBoardWithRules(rules)
+	if position != "" {
+		var err error
+		if board, err = game.ParsePositionWithRules(position, rules); err != nil {
+			return err
+		}
 	}
 
-	inputs := strings.Split(input, " ")
-	var from *game.Position
-	var to *game.Position
-	var piece game.Piece
-
-	if moveIsPlacingNewPiece(inputs) {
-		to = board.Get(parseCoords(inputs[0]))
-		size := letterToSize(inputs[1][0])
-		piece = game.Piece{Owner: board.ActivePlayer, Size: size}
-	} else {
-		from = board.Get(parseCoords(inputs[0]))
-		to = board.Get(parseCoords(inputs[1]))
+	start := time.Now()
+	tablebase, err := ai.Solve(board, maxPositions)
+	if err != nil {
+		return err
 	}
+	outcome, distance, _ := tablebase.Probe(board)
+	fmt.Printf("Solved %v positions in %v\n", tablebase.Len(), time.Since(start).Round(time.Millisecond))
+	fmt.Printf("%v to move: %v in %v plies\n", board.ActivePlayer, outcome, distance)
 
-	return game.Move{Piece: piece, From: from, To: to}, nil
+	return ai.SaveTablebase(path, tablebase)
 }
 
-func parseCoords(input string) (row, col int) {
-	row = int(input[1]) - '0' - 1
-	col = letterToColIndex(input[0])
-	return row, col
-}
-
-func letterToColIndex(letter uint8) int {
-	switch unicode.ToLower(rune(letter)) {
-	case 'a':
-		return 0
-	case 'b':
-		return 1
-	case 'c':
-		return 2
-	default:
-		panic("invalid col letter")
+// GenerateOpeningBook searches all positions within the first plies of the game and writes the opening book to the file at path
+func GenerateOpeningBook(plies int, rules game.Rules, limits ai.Limits, path string) error {
+	start := time.Now()
+	book, err := ai.GenerateOpeningBook(context.Background(), game.NewBoardWithRules(rules), plies, ai.NewMinimax(), limits)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("Searched %v positions in %v\n", book.Len(), time.Since(start).Round(time.Millisecond))
+	return ai.SaveOpeningBook(path, book)
 }
 
-func letterToSize(letter uint8) game.Size {
-	switch unicode.ToLower(rune(letter)) {
-	case 's':
-		return game.Small
-	case 'm':
-		return game.Medium
-	case 'l':
-		return game.Large
-	default:
-		panic("invalid size letter")
+// SaveRecord appends the record of a game to the file at path.
+func SaveRecord(path string, record *game.GameRecord) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+	return game.WriteRecord(file, record)
 }
 
-func sizeToLetter(size game.Size) string {
-	switch size {
-	case game.Small:
-		return "S"
-	case game.Medium:
-		return "M"
-	case game.Large:
-		return "L"
-	default:
-		return "?"
+// ReplayRecords prints every game stored in the file at path move by move.
+func ReplayRecords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
 	}
-}
+	defer file.Close()
 
-func colIndexToLetter(col int) string {
-	switch col {
-	case 0:
-		return "a"
-	case 1:
-		return "b"
-	case 2:
-		return "c"
-	default:
-		return "?"
+	records, err := game.ReadRecords(file)
+	if err != nil {
+		return err
 	}
-}
 
-func moveIsPlacingNewPiece(inputs []string) bool {
-	return len(inputs[1]) == 1
+	for _, record := range records {
+		fmt.Printf("%v vs. %v (%v)\n", record.Player1, record.Player2, record.Start.Format(time.DateTime))
+		board, err := (&game.GameRecord{Rules: record.Rules, Position: record.Position}).Replay()
+		if err != nil {
+			return err
+		}
+		printBoard(board)
+		for _, input := range record.Moves {
+			move, err := game.ParseMove(input, board)
+			if err != nil {
+				return err
+			}
+			if err := board.MakeMove(move); err != nil {
+				return err
+			}
+			fmt.Printf("%v: %v\n", board.ActivePlayer.Opponent(), input)
+			printBoard(board)
+		}
+		fmt.Println("Result:", record.Result)
+	}
+	return nil
 }
 
-func MoveString(move game.Move) string {
-	if move.PlacesNewPiece() {
-		return fmt.Sprintf("%v %v", PositionString(move.To), sizeToLetter(move.Piece.Size))
-	} else {
-		return fmt.Sprintf("%v %v", PositionString(move.From), PositionString(move.To))
+// RunTournament plays the tournament, prints every finished game and the final report,
+// and appends the records of all games to the file at path if path is not empty
+func RunTournament(config tournament.Config, path string) error {
+	start := time.Now()
+	played := 0
+	result, err := tournament.Run(context.Background(), config, func(g tournament.GameResult) {
+		played++
+		fmt.Printf("Game %d: %v vs. %v: %v in %d plies\n", played, g.Record.Player1, g.Record.Player2,
+			g.Record.Result, len(g.Record.Moves))
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Played %d games in %v\n\n", len(result.Games), time.Since(start).Round(time.Millisecond))
+	if err := result.WriteReport(os.Stdout); err != nil {
+		return err
 	}
-}
 
-func PositionString(p *game.Position) string {
-	return fmt.Sprintf("%v%v", colIndexToLetter(p.Col), p.Row+1)
+	if path == "" {
+		return nil
+	}
+	for _, g := range result.Games {
+		if err := SaveRecord(path, g.Record); err != nil {
+			return err
+		}
+	}
+	return nil
 }
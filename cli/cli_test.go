@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeHumanMoveCommandsKeepTurn(t *testing.T) {
+	defer func(original *bufio.Reader) { input = original }(input)
+	input = bufio.NewReader(strings.NewReader("undo\nredo\nundo\nc3 M\n"))
+
+	board := game.NewBoard()
+	history := &moveHistory{}
+	first := game.NewMove(game.Player1, board.Get(1, 1), game.Large)
+	board.MustMakeMove(first)
+	history.push(first)
+
+	assert.NoError(t, makeHumanMove(board, history))
+	assert.Equal(t, []game.Move{game.NewMove(game.Player1, board.Get(2, 2), game.Medium)}, history.played,
+		"the turn ends with the first move, not with a command")
+	assert.Equal(t, game.Player2, board.ActivePlayer)
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// moveHistory keeps track of the moves played on a board so they can be undone and redone.
+type moveHistory struct {
+	played []game.Move
+	undone []game.Move
+}
+
+// push records a newly played move. Moves that were undone before can not be redone afterwards.
+func (h *moveHistory) push(move game.Move) {
+	h.played = append(h.played, move)
+	h.undone = nil
+}
+
+func (h *moveHistory) undo(board *game.Board) error {
+	if len(h.played) == 0 {
+		return errors.New("no move to undo")
+	}
+	move := h.played[len(h.played)-1]
+	h.played = h.played[:len(h.played)-1]
+	board.MustUndoMove(move)
+	h.undone = append(h.undone, move)
+	return nil
+}
+
+func (h *moveHistory) redo(board *game.Board) error {
+	if len(h.undone) == 0 {
+		return errors.New("no move to redo")
+	}
+	move := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	board.MustMakeMove(move)
+	h.played = append(h.played, move)
+	return nil
+}
+
+// takeback undoes the last move of the opponent and the last move of the active player.
+func (h *moveHistory) takeback(board *game.Board) error {
+	if len(h.played) < 2 {
+		return errors.New("no move to take back")
+	}
+	if err := h.undo(board); err != nil {
+		return err
+	}
+	return h.undo(board)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveHistoryUndoRedo(t *testing.T) {
+	board := game.NewBoard()
+	history := &moveHistory{}
+	assert.Error(t, history.undo(board))
+	assert.Error(t, history.redo(board))
+
+	first := game.NewMove(game.Player1, board.Get(1, 1), game.Large)
+	board.MustMakeMove(first)
+	history.push(first)
+	second := game.NewMove(game.Player2, board.Get(0, 0), game.Small)
+	board.MustMakeMove(second)
+	history.push(second)
+	afterSecond := board.Hash
+
+	assert.NoError(t, history.undo(board))
+	assert.NoError(t, history.undo(board))
+	assert.Equal(t, game.NewBoard().Hash, board.Hash)
+	assert.Error(t, history.undo(board))
+
+	assert.NoError(t, history.redo(board))
+	assert.NoError(t, history.redo(board))
+	assert.Equal(t, afterSecond, board.Hash)
+	assert.Equal(t, []game.Move{first, second}, history.played)
+	assert.Error(t, history.redo(board))
+}
+
+func TestMoveHistoryPushClearsRedo(t *testing.T) {
+	board := game.NewBoard()
+	history := &moveHistory{}
+	first := game.NewMove(game.Player1, board.Get(1, 1), game.Large)
+	board.MustMakeMove(first)
+	history.push(first)
+
+	assert.NoError(t, history.undo(board))
+	other := game.NewMove(game.Player1, board.Get(2, 2), game.Medium)
+	board.MustMakeMove(other)
+	history.push(other)
+
+	assert.Error(t, history.redo(board), "undone moves can not be redone after a new move")
+	assert.Equal(t, []game.Move{other}, history.played)
+}
+
+func TestMoveHistoryTakeback(t *testing.T) {
+	board := game.NewBoard()
+	history := &moveHistory{}
+	first := game.NewMove(game.Player1, board.Get(1, 1), game.Large)
+	board.MustMakeMove(first)
+	history.push(first)
+	assert.Error(t, history.takeback(board), "a single move can not be taken back")
+	assert.Equal(t, game.Player2, board.ActivePlayer)
+
+	second := game.NewMove(game.Player2, board.Get(0, 0), game.Small)
+	board.MustMakeMove(second)
+	history.push(second)
+	assert.NoError(t, history.takeback(board))
+	assert.Equal(t, game.NewBoard().Hash, board.Hash)
+	assert.Empty(t, history.played)
+
+	assert.NoError(t, history.redo(board))
+	assert.Equal(t, []game.Move{first}, history.played, "takeback can be redone move by move")
+}
@@ -3,17 +3,31 @@ package game
 import (
 	"errors"
 	"fmt"
+	"slices"
 )
 
 type Board struct {
-	Grid            [3][3]Position
-	Lines           [8]Line
+	Rules           Rules
+	Grid            [][]Position
+	Lines           []Line // All rows, columns and diagonals of Rules.WinLength positions
 	RemainingPieces map[Player][]int
 	ActivePlayer    Player
 	Hash            uint64
+	DrawRules       DrawRules
+	symmetryHashes  [symmetryCount]uint64 // Hashes of all symmetric variants of the board, indexed by Symmetry
+	history         []uint64              // Hashes of the positions before every move made on the board
 }
 
-type Line [3]*Position
+// DrawRules end games that can not be decided as draws. A zero value disables the rule.
+type DrawRules struct {
+	Repetitions int // The game is drawn when the same position occurs for the Repetitions-th time
+	MaxPlies    int // The game is drawn when no player has won after MaxPlies moves made on the board
+}
+
+// DefaultDrawRules draws games by threefold repetition
+var DefaultDrawRules = DrawRules{Repetitions: 3}
+
+type Line []*Position
 
 type Position struct {
 	Row    int
@@ -21,22 +35,57 @@ type Position struct {
 	Pieces []Piece
 }
 
+// NewBoard creates an empty board with the default rules
 func NewBoard() *Board {
+	return NewBoardWithRules(DefaultRules)
+}
+
+// NewBoardWithRules creates an empty board for the variant described by the rules.
+// It panics if the rules are invalid.
+func NewBoardWithRules(rules Rules) *Board {
+	if err := rules.Validate(); err != nil {
+		panic(err)
+	}
 	board := &Board{
+		Rules: rules,
 		RemainingPieces: map[Player][]int{
-			Player1: {2, 2, 2},
-			Player2: {2, 2, 2},
+			Player1: slices.Repeat([]int{rules.PiecesPerSize}, rules.PieceSizes),
+			Player2: slices.Repeat([]int{rules.PiecesPerSize}, rules.PieceSizes),
 		},
 		ActivePlayer: Player1,
-		Hash:         GetPlayerZobristValue(Player1),
+		Hash:         GetPlayerZobristValue(Player1) ^ GetRulesZobristValue(rules),
+		DrawRules:    DefaultDrawRules,
+	}
+	for s := range board.symmetryHashes {
+		board.symmetryHashes[s] = board.Hash
 	}
 	board.initializePositions()
 	board.initializeLines()
 	return board
 }
 
+// Clone returns an independent copy of the board including its history, so it can be searched by another goroutine.
+// Moves refer to the positions of a board, Board.TransformMove with the identity maps them onto the copy.
+func (b *Board) Clone() *Board {
+	clone := NewBoardWithRules(b.Rules)
+	for r := range b.Grid {
+		for c := range b.Grid[r] {
+			clone.Grid[r][c].Pieces = slices.Clone(b.Grid[r][c].Pieces)
+		}
+	}
+	for player, remaining := range b.RemainingPieces {
+		clone.RemainingPieces[player] = slices.Clone(remaining)
+	}
+	clone.ActivePlayer = b.ActivePlayer
+	clone.Hash = b.Hash
+	clone.DrawRules = b.DrawRules
+	clone.symmetryHashes = b.symmetryHashes
+	clone.history = slices.Clone(b.history)
+	return clone
+}
+
 func (b *Board) Get(row, col int) *Position {
-	if row < 0 || row >= 3 || col < 0 || col >= 3 {
+	if row < 0 || row >= b.Rules.BoardSize || col < 0 || col >= b.Rules.BoardSize {
 		panic("can not get position out of bounds")
 	}
 	return &b.Grid[row][col]
@@ -55,6 +104,7 @@ func (b *Board) MakeMove(move Move) error {
 		return err
 	}
 
+	b.history = append(b.history, b.Hash)
 	if move.MovesExistingPiece() {
 		pieceToMove := move.From.TopPiece()
 		b.removePiece(move.From)
@@ -78,10 +128,13 @@ func (b *Board) MustUndoMove(move Move) {
 	}
 
 	b.switchActivePlayer()
+	if len(b.history) > 0 {
+		b.history = b.history[:len(b.history)-1]
+	}
 }
 
 func (b *Board) placePiece(p *Position, piece Piece) {
-	b.Hash ^= GetZobristValue(p, piece)
+	b.updateHashes(p, piece)
 	p.Pieces = append(p.Pieces, piece)
 	b.RemainingPieces[piece.Owner][piece.Size]--
 }
@@ -91,11 +144,20 @@ func (b *Board) removePiece(p *Position) {
 	if topPiece == nil {
 		panic("attempting to remove piece from empty position")
 	}
-	b.Hash ^= GetZobristValue(p, *topPiece)
+	b.updateHashes(p, *topPiece)
 	p.Pieces = p.Pieces[:len(p.Pieces)-1]
 	b.RemainingPieces[topPiece.Owner][topPiece.Size]++
 }
 
+// updateHashes toggles the piece on the position in the hash of the board and in the hashes of its symmetric variants.
+func (b *Board) updateHashes(p *Position, piece Piece) {
+	b.Hash ^= GetZobristValue(p, piece)
+	for s := range b.symmetryHashes {
+		row, col := Symmetry(s).Transform(p.Row, p.Col, b.Rules.BoardSize)
+		b.symmetryHashes[s] ^= GetZobristValue(&b.Grid[row][col], piece)
+	}
+}
+
 func (p Position) TopPiece() *Piece {
 	if len(p.Pieces) == 0 {
 		return nil // No pieces in this cell
@@ -105,42 +167,107 @@ func (p Position) TopPiece() *Piece {
 	return &topPiece
 }
 
+// CheckWin returns the winner of the game, or None if the game is not over yet or drawn
 func (b *Board) CheckWin() Player {
+	return b.Outcome().Winner()
+}
+
+// Outcome returns the outcome of the game. A game is won by a complete line or when the player to move has no
+// legal moves, and drawn according to the draw rules.
+func (b *Board) Outcome() Outcome {
 	// 1. Check for standard 3-in-a-row win
 	if winner := b.CheckLineWin(); winner != None {
-		return winner
+		return WinOf(winner)
+	}
+
+	// 2. Check for repetitions and the move limit
+	if b.IsDraw() {
+		return Drawn
 	}
 
-	// 2. Check if the active player has no legal moves
+	// 3. Check if the active player has no legal moves
 	// If the active player cannot make any move, they lose (so the opponent wins).
 	if !b.HasAnyLegalMove() {
-		return b.ActivePlayer.Opponent()
+		return WinOf(b.ActivePlayer.Opponent())
+	}
+
+	return Undecided // No winner yet
+}
+
+// IsDraw reports whether the game is drawn by the draw rules. Line wins are not considered.
+func (b *Board) IsDraw() bool {
+	if b.DrawRules.MaxPlies > 0 && len(b.history) >= b.DrawRules.MaxPlies {
+		return true
 	}
+	return b.DrawRules.Repetitions > 0 && b.Repetitions() >= b.DrawRules.Repetitions
+}
+
+// Repetitions returns how often the current position occurred in the moves made on the board, including now
+func (b *Board) Repetitions() int {
+	count := 1
+	// Positions with the same player to move are an even number of moves apart
+	for i := len(b.history) - 2; i >= 0; i -= 2 {
+		if b.history[i] == b.Hash {
+			count++
+		}
+	}
+	return count
+}
 
-	return None // No winner yet
+// Plies returns the number of moves made on the board that have not been undone
+func (b *Board) Plies() int {
+	return len(b.history)
 }
 
+// CheckLineWin returns the player with a complete line. If both players have a line, which is only possible
+// after uncovering a line of the opponent, the uncover rule decides the winner.
 func (b *Board) CheckLineWin() Player {
+	player1Line, player2Line := false, false
 	for _, line := range b.Lines {
-		if winner := line.CheckWin(); winner != None {
-			return winner
+		switch line.CheckWin() {
+		case Player1:
+			player1Line = true
+		case Player2:
+			player2Line = true
 		}
 	}
-	return None
+
+	switch {
+	case player1Line && player2Line:
+		mover := b.ActivePlayer.Opponent()
+		if b.Rules.Uncover == UncoverLoses {
+			return mover.Opponent()
+		}
+		return mover
+	case player1Line:
+		return Player1
+	case player2Line:
+		return Player2
+	default:
+		return None
+	}
 }
 
-func (l Line) CheckWin() Player {
-	p1 := l[0].TopPiece()
-	p2 := l[1].TopPiece()
-	p3 := l[2].TopPiece()
+func (b *Board) hasLine(player Player) bool {
+	for _, line := range b.Lines {
+		if line.CheckWin() == player {
+			return true
+		}
+	}
+	return false
+}
 
-	if p1 == nil || p2 == nil || p3 == nil {
-		return None // At least one empty cell, no win
+func (l Line) CheckWin() Player {
+	first := l[0].TopPiece()
+	if first == nil {
+		return None // Empty cell, no win
 	}
-	if p1.Owner == p2.Owner && p2.Owner == p3.Owner {
-		return p1.Owner // Return winning player
+	for _, p := range l[1:] {
+		if top := p.TopPiece(); top == nil || top.Owner != first.Owner {
+			return None // Empty cell or piece of the other player, no win
+		}
 	}
-	return None
+	return first.Owner // Return winning player
 }
 
 func (b *Board) HasAnyLegalMove() bool {
@@ -164,9 +291,11 @@ func (b *Board) GetPossibleMoves() []Move {
 // IteratePossibleMoves iterates over all possible moves and calls the callback function for each valid move.
 // If the callback returns false, the iteration stops.
 func (b *Board) IteratePossibleMoves(callback func(move Move) bool) {
+	size := b.Rules.BoardSize
+
 	// Try placing new pieces
-	for row := 0; row < 3; row++ {
-		for col := 0; col < 3; col++ {
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
 			for _, size := range b.AvailablePieceSizes(b.ActivePlayer) {
 				move := NewMove(b.ActivePlayer, b.Get(row, col), size)
 				valid, _ := b.IsValidMove(move)
@@ -180,8 +309,8 @@ func (b *Board) IteratePossibleMoves(callback func(move Move) bool) {
 	}
 
 	//Try moving already placed pieces
-	for fromRow := 0; fromRow < 3; fromRow++ {
-		for fromCol := 0; fromCol < 3; fromCol++ {
+	for fromRow := 0; fromRow < size; fromRow++ {
+		for fromCol := 0; fromCol < size; fromCol++ {
 			fromPos := b.Get(fromRow, fromCol)
 			topPiece := fromPos.TopPiece()
 
@@ -196,8 +325,8 @@ func (b *Board) IteratePossibleMoves(callback func(move Move) bool) {
 			}
 
 			// Try moving to every other position
-			for toRow := 0; toRow < 3; toRow++ {
-				for toCol := 0; toCol < 3; toCol++ {
+			for toRow := 0; toRow < size; toRow++ {
+				for toCol := 0; toCol < size; toCol++ {
 					toPos := b.Get(toRow, toCol)
 
 					// Don't move to the same position
@@ -263,18 +392,22 @@ func (b *Board) IsValidMove(move Move) (bool, error) {
 		}
 
 		// Check that moving the piece would not cause the other player to win
-		originalStack := from.Pieces
-		// Temporarily remove the top piece
-		from.Pieces = originalStack[:len(originalStack)-1]
-
-		// Check if the opponent wins
-		winner := b.CheckLineWin()
-
-		// Restore board state
-		from.Pieces = originalStack
-		// If opponent wins, this move is invalid
-		if winner != None {
-			return false, errors.New("moving piece would cause the other player to win")
+		if b.Rules.Uncover == UncoverForbidden {
+			originalStack := from.Pieces
+			// Temporarily remove the top piece
+			from.Pieces = originalStack[:len(originalStack)-1]
+
+			// Check if the opponent wins
+			winner := b.CheckLineWin()
+
+			// Restore board state
+			from.Pieces = originalStack
+			// If opponent wins, this move is invalid
+			if winner != None {
+				return false, errors.New("moving piece would cause the other player to win")
+			}
+		} else if b.Rules.Uncover == UncoverOwnLine && !b.completesOwnLineIfUncovering(from, to) {
+			return false, errors.New("moving piece would uncover a line of the other player without completing an own line")
 		}
 	}
 
@@ -288,6 +421,13 @@ func (b *Board) IsValidMove(move Move) (bool, error) {
 		piece = *from.TopPiece()
 	}
 
+	// With external stacks, new pieces may only gobble pieces of the opponent that are part of a threatening line
+	if b.Rules.ExternalStacks && move.PlacesNewPiece() {
+		if top := to.TopPiece(); top.Owner == b.ActivePlayer || !b.completesThreat(to, top.Owner) {
+			return false, errors.New("new pieces may only gobble pieces of a line of the opponent with one piece missing")
+		}
+	}
+
 	// Ensure the piece is larger than the already placed piece
 	if piece.Size <= to.TopPiece().Size {
 		return false, errors.New("piece not larger than existing piece on position")
@@ -296,52 +436,123 @@ func (b *Board) IsValidMove(move Move) (bool, error) {
 	return true, nil
 }
 
+// completesOwnLineIfUncovering reports whether moving the top piece from one position to another either uncovers
+// no line of the opponent or completes a line of the active player at the same time
+func (b *Board) completesOwnLineIfUncovering(from, to *Position) bool {
+	originalFrom, originalTo := from.Pieces, to.Pieces
+	piece := originalFrom[len(originalFrom)-1]
+
+	// Temporarily lift the piece
+	from.Pieces = originalFrom[:len(originalFrom)-1]
+	uncovers := b.hasLine(b.ActivePlayer.Opponent())
+	// Temporarily place the piece at the destination
+	to.Pieces = append(originalTo[:len(originalTo):len(originalTo)], piece)
+	completes := b.hasLine(b.ActivePlayer)
+
+	// Restore board state
+	from.Pieces, to.Pieces = originalFrom, originalTo
+	return !uncovers || completes
+}
+
+// AvailablePieceSizes returns the sizes of the pieces the player can place on the board
 func (b *Board) AvailablePieceSizes(player Player) (sizes []Size) {
-	if b.RemainingPieces[player][Small] > 0 {
-		sizes = append(sizes, Small)
+	for size := range b.RemainingPieces[player] {
+		if b.hasPieceAvailable(Piece{Owner: player, Size: Size(size)}) {
+			sizes = append(sizes, Size(size))
+		}
 	}
-	if b.RemainingPieces[player][Medium] > 0 {
-		sizes = append(sizes, Medium)
+	return sizes
+}
+
+func (b *Board) hasPieceAvailable(piece Piece) bool {
+	if int(piece.Size) >= b.Rules.PieceSizes || b.RemainingPieces[piece.Owner][piece.Size] < 1 {
+		return false
 	}
-	if b.RemainingPieces[player][Large] > 0 {
-		sizes = append(sizes, Large)
+	if !b.Rules.ExternalStacks || int(piece.Size) == b.Rules.PieceSizes-1 {
+		return true
 	}
+	// A smaller piece is the top of a stack if fewer stacks contain the next larger size
+	return b.RemainingPieces[piece.Owner][piece.Size] > b.RemainingPieces[piece.Owner][piece.Size+1]
+}
 
-	return sizes
+// StackHeights returns the number of pieces in each external stack of the player, from the highest to the lowest stack.
+// The top piece of a stack of height h has size h-1. Only meaningful with Rules.ExternalStacks.
+func (b *Board) StackHeights(player Player) []int {
+	heights := make([]int, b.Rules.PiecesPerSize)
+	for stack := range heights {
+		// A stack contains a size if more stacks than the higher ones still contain that size
+		for _, count := range b.RemainingPieces[player] {
+			if count > stack {
+				heights[stack]++
+			}
+		}
+	}
+	return heights
 }
 
-func (b *Board) hasPieceAvailable(piece Piece) bool {
-	return b.RemainingPieces[piece.Owner][piece.Size] >= 1
+// completesThreat reports whether the position is part of a line in which the player has all but one position
+func (b *Board) completesThreat(p *Position, player Player) bool {
+	for _, line := range b.Lines {
+		if !slices.Contains(line, p) {
+			continue
+		}
+		count := 0
+		for _, position := range line {
+			if top := position.TopPiece(); top != nil && top.Owner == player {
+				count++
+			}
+		}
+		if count == len(line)-1 {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *Board) switchActivePlayer() {
-	b.Hash ^= GetPlayerZobristValue(b.ActivePlayer)
+	playerHashes := GetPlayerZobristValue(b.ActivePlayer) ^ GetPlayerZobristValue(b.ActivePlayer.Opponent())
 	b.ActivePlayer = b.ActivePlayer.Opponent()
-	b.Hash ^= GetPlayerZobristValue(b.ActivePlayer)
+	b.Hash ^= playerHashes
+	for s := range b.symmetryHashes {
+		b.symmetryHashes[s] ^= playerHashes
+	}
 }
 
 func (b *Board) initializePositions() {
-	for r := 0; r < 3; r++ {
-		for c := 0; c < 3; c++ {
+	size := b.Rules.BoardSize
+	b.Grid = make([][]Position, size)
+	for r := 0; r < size; r++ {
+		b.Grid[r] = make([]Position, size)
+		for c := 0; c < size; c++ {
 			b.Grid[r][c] = Position{Row: r, Col: c, Pieces: []Piece{}}
 		}
 	}
 }
 
+// initializeLines creates every row, column and diagonal segment of Rules.WinLength positions
 func (b *Board) initializeLines() {
-	// Rows
-	for r := 0; r < 3; r++ {
-		b.Lines[r] = Line{&b.Grid[r][0], &b.Grid[r][1], &b.Grid[r][2]}
+	size, length := b.Rules.BoardSize, b.Rules.WinLength
+	directions := [][2]int{
+		{0, 1},  // Rows
+		{1, 0},  // Columns
+		{1, 1},  // Diagonals
+		{1, -1}, // Anti-diagonals
 	}
 
-	// Columns
-	for c := 0; c < 3; c++ {
-		b.Lines[c+3] = Line{
-			&b.Grid[0][c], &b.Grid[1][c], &b.Grid[2][c],
+	b.Lines = nil
+	for _, direction := range directions {
+		for r := 0; r < size; r++ {
+			for c := 0; c < size; c++ {
+				endRow, endCol := r+direction[0]*(length-1), c+direction[1]*(length-1)
+				if endRow < 0 || endRow >= size || endCol < 0 || endCol >= size {
+					continue
+				}
+				line := make(Line, length)
+				for i := range line {
+					line[i] = &b.Grid[r+direction[0]*i][c+direction[1]*i]
+				}
+				b.Lines = append(b.Lines, line)
+			}
 		}
 	}
-
-	// Diagonals
-	b.Lines[6] = Line{&b.Grid[0][0], &b.Grid[1][1], &b.Grid[2][2]}
-	b.Lines[7] = Line{&b.Grid[0][2], &b.Grid[1][1], &b.Grid[2][0]}
 }
@@ -123,6 +123,24 @@ func TestUndoMove(t *testing.T) {
 
 }
 
+func TestClone(t *testing.T) {
+	board := NewBoard()
+	board.MustMakeMove(NewMove(Player1, board.Get(1, 1), Small))
+	board.MustMakeMove(NewMove(Player2, board.Get(1, 1), Medium))
+
+	encoded := board.Encode()
+	clone := board.Clone()
+	assert.Equal(t, encoded, clone.Encode())
+	assert.Equal(t, board.Hash, clone.Hash)
+	assert.Equal(t, board.symmetryHashes, clone.symmetryHashes)
+
+	clone.MustMakeMove(NewMove(Player1, clone.Get(1, 1), Large))
+	clone.MustMakeMove(NewMove(Player2, clone.Get(0, 0), Large))
+	assert.Equal(t, encoded, board.Encode(), "moves on the clone must not change the board")
+	assert.Equal(t, 2, board.Plies())
+	assert.Equal(t, 4, clone.Plies())
+}
+
 func TestLossDueToNoLegalMoves(t *testing.T) {
 	board := NewBoard()
 
@@ -167,3 +185,38 @@ func TestLossDueToNoLegalMoves(t *testing.T) {
 	assert.False(t, board.HasAnyLegalMove(), "Player 1 should have no legal moves")
 	assert.Equal(t, 0, len(board.GetPossibleMoves()), "Player 1 should have 0 possible moves")
 }
+
+func TestDrawByRepetition(t *testing.T) {
+	board := NewBoard()
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 0), Small))
+	board.MustMakeMove(NewMove(Player2, board.Get(2, 2), Small))
+
+	// Both players move their pieces back and forth
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, None, board.CheckWin(), "position must not be drawn after %d repetitions", board.Repetitions())
+		board.MustMakeMove(NewMoveExisting(board.Get(0, 0), board.Get(0, 1)))
+		board.MustMakeMove(NewMoveExisting(board.Get(2, 2), board.Get(2, 1)))
+		board.MustMakeMove(NewMoveExisting(board.Get(0, 1), board.Get(0, 0)))
+		board.MustMakeMove(NewMoveExisting(board.Get(2, 1), board.Get(2, 2)))
+	}
+	assert.Equal(t, 3, board.Repetitions())
+	assert.Equal(t, Drawn, board.Outcome(), "threefold repetition must be a draw")
+
+	undoMove := NewMoveExisting(board.Get(2, 1), board.Get(2, 2))
+	board.MustUndoMove(undoMove)
+	assert.Equal(t, None, board.CheckWin(), "undoing a move must undo the repetition")
+
+	board.DrawRules = DrawRules{}
+	board.MustMakeMove(undoMove)
+	assert.Equal(t, None, board.CheckWin(), "repetitions must not draw without draw rules")
+}
+
+func TestDrawByMaxPlies(t *testing.T) {
+	board := NewBoard()
+	board.DrawRules.MaxPlies = 2
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 0), Small))
+	assert.Equal(t, None, board.CheckWin())
+	board.MustMakeMove(NewMove(Player2, board.Get(1, 1), Small))
+	assert.Equal(t, 2, board.Plies())
+	assert.Equal(t, Drawn, board.Outcome(), "game must be drawn after MaxPlies plies")
+}
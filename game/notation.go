@@ -0,0 +1,100 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// RegexMovePattern matches the notation of moves on the largest board, see ParseMove
+const RegexMovePattern = "^(?i)[a-e][1-5] ([smlx]|[a-e][1-5])$"
+
+var movePattern = regexp.MustCompile(RegexMovePattern)
+
+// ParseMove parses a move in the notation "b2 S" (placing a new piece) or "a1 c3" (moving an existing piece)
+// for the active player of the board.
+func ParseMove(input string, board *Board) (Move, error) {
+	if !movePattern.MatchString(input) {
+		return Move{}, errors.New("invalid move input")
+	}
+
+	inputs := strings.Split(input, " ")
+	var from *Position
+	var to *Position
+	var piece Piece
+
+	if moveIsPlacingNewPiece(inputs) {
+		row, col := parseCoords(inputs[0])
+		size := letterToSize(inputs[1][0])
+		if !board.onBoard(row, col) || int(size) >= board.Rules.PieceSizes {
+			return Move{}, errors.New("invalid move input")
+		}
+		to = board.Get(row, col)
+		piece = Piece{Owner: board.ActivePlayer, Size: size}
+	} else {
+		fromRow, fromCol := parseCoords(inputs[0])
+		toRow, toCol := parseCoords(inputs[1])
+		if !board.onBoard(fromRow, fromCol) || !board.onBoard(toRow, toCol) {
+			return Move{}, errors.New("invalid move input")
+		}
+		from = board.Get(fromRow, fromCol)
+		to = board.Get(toRow, toCol)
+	}
+
+	return Move{Piece: piece, From: from, To: to}, nil
+}
+
+func (b *Board) onBoard(row, col int) bool {
+	return row >= 0 && row < b.Rules.BoardSize && col >= 0 && col < b.Rules.BoardSize
+}
+
+func parseCoords(input string) (row, col int) {
+	row = int(input[1]) - '0' - 1
+	col = letterToColIndex(input[0])
+	return row, col
+}
+
+func letterToColIndex(letter uint8) int {
+	return int(unicode.ToLower(rune(letter)) - 'a')
+}
+
+func letterToSize(letter uint8) Size {
+	size := strings.IndexRune(sizeLetters, unicode.ToUpper(rune(letter)))
+	if size < 0 {
+		panic("invalid size letter")
+	}
+	return Size(size)
+}
+
+func sizeToLetter(size Size) string {
+	if size < 0 || int(size) >= len(sizeLetters) {
+		return "?"
+	}
+	return sizeLetters[size : size+1]
+}
+
+func colIndexToLetter(col int) string {
+	if col < 0 || col >= MaxBoardSize {
+		return "?"
+	}
+	return string(rune('a' + col))
+}
+
+func moveIsPlacingNewPiece(inputs []string) bool {
+	return len(inputs[1]) == 1
+}
+
+// MoveString returns the move in the notation accepted by ParseMove.
+func MoveString(move Move) string {
+	if move.PlacesNewPiece() {
+		return fmt.Sprintf("%v %v", PositionString(move.To), sizeToLetter(move.Piece.Size))
+	} else {
+		return fmt.Sprintf("%v %v", PositionString(move.From), PositionString(move.To))
+	}
+}
+
+func PositionString(p *Position) string {
+	return fmt.Sprintf("%v%v", colIndexToLetter(p.Col), p.Row+1)
+}
@@ -0,0 +1,53 @@
+package game
+
+// Outcome is the state of a game: undecided, won by one of the players or drawn
+type Outcome int
+
+const (
+	Undecided Outcome = iota // The game is not over yet
+	Player1Wins
+	Player2Wins
+	Drawn // The game ended without a winner according to the draw rules
+)
+
+// WinOf returns the outcome of a game won by the player
+func WinOf(player Player) Outcome {
+	switch player {
+	case Player1:
+		return Player1Wins
+	case Player2:
+		return Player2Wins
+	default:
+		panic("only Player 1 and Player 2 can win")
+	}
+}
+
+// Winner returns the player who won the game, or None if the game is undecided or drawn
+func (o Outcome) Winner() Player {
+	switch o {
+	case Player1Wins:
+		return Player1
+	case Player2Wins:
+		return Player2
+	default:
+		return None
+	}
+}
+
+// IsOver reports whether the game is won or drawn
+func (o Outcome) IsOver() bool {
+	return o != Undecided
+}
+
+func (o Outcome) String() string {
+	switch o {
+	case Player1Wins:
+		return "Player 1 wins"
+	case Player2Wins:
+		return "Player 2 wins"
+	case Drawn:
+		return "Draw"
+	default:
+		return "Undecided"
+	}
+}
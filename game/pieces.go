@@ -14,9 +14,10 @@ const (
 )
 
 const (
-	Small  Size = 0
-	Medium Size = 1
-	Large  Size = 2
+	Small      Size = 0
+	Medium     Size = 1
+	Large      Size = 2
+	ExtraLarge Size = 3 // Only used by rules with four piece sizes
 )
 
 type Piece struct {
@@ -25,15 +26,7 @@ type Piece struct {
 }
 
 func (piece Piece) String() string {
-	var size string
-	switch piece.Size {
-	case Small:
-		size = "S"
-	case Medium:
-		size = "M"
-	case Large:
-		size = "L"
-	}
+	size := sizeToLetter(piece.Size)
 	if piece.Owner == Player1 {
 		return color.RedString(size)
 	} else {
@@ -41,14 +34,20 @@ func (piece Piece) String() string {
 	}
 }
 
+// ID returns a unique number between 0 and 2*MaxPieceSizes-1 for every combination of owner and size
 func (piece Piece) ID() int {
 	if piece.Owner == Player1 {
 		return int(piece.Size)
 	} else {
-		return int(piece.Owner) + 3
+		return int(piece.Size) + MaxPieceSizes
 	}
 }
 
+// String returns the letter of the size used in the move notation and the position encoding
+func (size Size) String() string {
+	return sizeToLetter(size)
+}
+
 func (player Player) String() string {
 	switch player {
 	case None:
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	emptyCell     = "-"
+	sizeLetters   = "SMLX" // Small, medium, large and extra large
+	player1Symbol = "1"
+	player2Symbol = "2"
+)
+
+// StartPosition is the encoding of the board returned by NewBoard.
+const StartPosition = "-,-,-/-,-,-/-,-,- 1 222/222"
+
+// Encode returns a compact text representation of the board that can be read back with ParsePosition.
+// The encoding consists of three space separated fields:
+//   - the grid, rows separated by '/' and cells by ','. Each cell lists its stack bottom-to-top,
+//     uppercase letters for Player 1 and lowercase letters for Player 2, '-' for an empty cell
+//   - the active player, '1' or '2'
+//   - the remaining pieces of every size of Player 1 and Player 2, separated by '/'
+//
+// The rules are not part of the encoding, a position must be parsed with the rules of the board.
+func (b *Board) Encode() string {
+	var sb strings.Builder
+	for row := 0; row < b.Rules.BoardSize; row++ {
+		if row > 0 {
+			sb.WriteByte('/')
+		}
+		for col := 0; col < b.Rules.BoardSize; col++ {
+			if col > 0 {
+				sb.WriteByte(',')
+			}
+			pieces := b.Get(row, col).Pieces
+			if len(pieces) == 0 {
+				sb.WriteString(emptyCell)
+			}
+			for _, piece := range pieces {
+				sb.WriteByte(pieceLetter(piece))
+			}
+		}
+	}
+
+	sb.WriteByte(' ')
+	if b.ActivePlayer == Player1 {
+		sb.WriteString(player1Symbol)
+	} else {
+		sb.WriteString(player2Symbol)
+	}
+
+	sb.WriteByte(' ')
+	for i, player := range []Player{Player1, Player2} {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		for _, count := range b.RemainingPieces[player] {
+			sb.WriteString(strconv.Itoa(count))
+		}
+	}
+	return sb.String()
+}
+
+// ParsePosition creates a board with the default rules from a string produced by Board.Encode.
+// The remaining pieces must match the pieces on the grid so that each player owns all pieces of the rules.
+// The hash of the board is always recomputed from the parsed position.
+func ParsePosition(s string) (*Board, error) {
+	return ParsePositionWithRules(s, DefaultRules)
+}
+
+// ParsePositionWithRules is like ParsePosition for a board of the variant described by the rules.
+func ParsePositionWithRules(s string, rules Rules) (*Board, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return nil, errors.New("position must consist of grid, active player and remaining pieces")
+	}
+
+	board := NewBoardWithRules(rules)
+	if err := board.parseGrid(fields[0]); err != nil {
+		return nil, err
+	}
+
+	switch fields[1] {
+	case player1Symbol:
+	case player2Symbol:
+		board.switchActivePlayer()
+	default:
+		return nil, fmt.Errorf("invalid active player %q", fields[1])
+	}
+
+	if err := board.checkRemainingPieces(fields[2]); err != nil {
+		return nil, err
+	}
+	if err := board.checkStacks(); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+func (b *Board) parseGrid(grid string) error {
+	size := b.Rules.BoardSize
+	rows := strings.Split(grid, "/")
+	if len(rows) != size {
+		return fmt.Errorf("grid must have %d rows, got %d", size, len(rows))
+	}
+
+	for row, rowString := range rows {
+		cells := strings.Split(rowString, ",")
+		if len(cells) != size {
+			return fmt.Errorf("row %d must have %d cells, got %d", row+1, size, len(cells))
+		}
+		for col, cell := range cells {
+			if cell == emptyCell {
+				continue
+			}
+			if err := b.parseStack(b.Get(row, col), cell); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Board) parseStack(p *Position, stack string) error {
+	for i := 0; i < len(stack); i++ {
+		piece, err := parsePieceLetter(stack[i])
+		if err != nil {
+			return err
+		}
+		if int(piece.Size) >= b.Rules.PieceSizes {
+			return fmt.Errorf("invalid piece %q for %d piece sizes", stack[i], b.Rules.PieceSizes)
+		}
+		if top := p.TopPiece(); top != nil && top.Size >= piece.Size {
+			return fmt.Errorf("stack %q must be ordered from smallest to largest piece", stack)
+		}
+		if b.RemainingPieces[piece.Owner][piece.Size] < 1 {
+			return fmt.Errorf("%v has too many pieces of size %c on the board", piece.Owner, sizeLetters[piece.Size])
+		}
+		b.placePiece(p, piece)
+	}
+	return nil
+}
+
+func (b *Board) checkRemainingPieces(remaining string) error {
+	counts := strings.Split(remaining, "/")
+	if len(counts) != 2 {
+		return errors.New("remaining pieces must be given for both players")
+	}
+
+	for i, player := range []Player{Player1, Player2} {
+		if len(counts[i]) != b.Rules.PieceSizes {
+			return fmt.Errorf("remaining pieces of %v must have %d digits", player, b.Rules.PieceSizes)
+		}
+		for size := range b.Rules.PieceSizes {
+			count, err := strconv.Atoi(counts[i][size : size+1])
+			if err != nil {
+				return fmt.Errorf("invalid remaining piece count %q", counts[i][size])
+			}
+			if count != b.RemainingPieces[player][size] {
+				return fmt.Errorf("%v must have %d remaining pieces of size %c, got %d",
+					player, b.RemainingPieces[player][size], sizeLetters[size], count)
+			}
+		}
+	}
+	return nil
+}
+
+// checkStacks verifies that the remaining pieces fit into external stacks, where larger pieces are played first
+func (b *Board) checkStacks() error {
+	if !b.Rules.ExternalStacks {
+		return nil
+	}
+	for _, player := range []Player{Player1, Player2} {
+		remaining := b.RemainingPieces[player]
+		for size := 1; size < len(remaining); size++ {
+			if remaining[size] > remaining[size-1] {
+				return fmt.Errorf("remaining pieces of %v do not fit into external stacks", player)
+			}
+		}
+	}
+	return nil
+}
+
+func pieceLetter(piece Piece) byte {
+	letter := sizeLetters[piece.Size]
+	if piece.Owner == Player2 {
+		return letter + 'a' - 'A'
+	}
+	return letter
+}
+
+func parsePieceLetter(letter byte) (Piece, error) {
+	if size := strings.IndexByte(sizeLetters, letter); size >= 0 {
+		return Piece{Owner: Player1, Size: Size(size)}, nil
+	}
+	if size := strings.IndexByte(sizeLetters, letter-'a'+'A'); size >= 0 {
+		return Piece{Owner: Player2, Size: Size(size)}, nil
+	}
+	return Piece{}, fmt.Errorf("invalid piece %q", letter)
+}
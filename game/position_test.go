@@ -0,0 +1,54 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeStartPosition(t *testing.T) {
+	board := NewBoard()
+	assert.Equal(t, StartPosition, board.Encode(), "new board must encode to the start position")
+
+	parsed, err := ParsePosition(StartPosition)
+	assert.NoError(t, err)
+	assert.Equal(t, board.Hash, parsed.Hash, "parsed start position must have the same hash as a new board")
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	board := NewBoard()
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 0), Small))
+	board.MustMakeMove(NewMove(Player2, board.Get(0, 0), Medium))
+	board.MustMakeMove(NewMove(Player1, board.Get(1, 1), Large))
+	board.MustMakeMove(NewMove(Player2, board.Get(2, 1), Small))
+	board.MustMakeMove(NewMoveExisting(board.Get(1, 1), board.Get(2, 1)))
+
+	encoded := board.Encode()
+	assert.Equal(t, "Sm,-,-/-,-,-/-,sL,- 2 121/112", encoded)
+
+	parsed, err := ParsePosition(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, board.Hash, parsed.Hash, "parsed board must have the same hash")
+	assert.Equal(t, board.ActivePlayer, parsed.ActivePlayer, "parsed board must have the same active player")
+	assert.Equal(t, board.RemainingPieces, parsed.RemainingPieces, "parsed board must have the same remaining pieces")
+	assert.Equal(t, encoded, parsed.Encode(), "parsed board must encode to the same string")
+}
+
+func TestParsePositionInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"-,-,-/-,-,- 1 222/222",
+		"-,-,-/-,-,-/-,-,-,- 1 222/222",
+		"-,-,-/-,-,-/-,-,- 3 222/222",
+		"X,-,-/-,-,-/-,-,- 1 222/222",
+		"MS,-,-/-,-,-/-,-,- 2 111/222",
+		"SS,-,-/-,-,-/-,-,- 2 022/222",
+		"S,S,S/-,-,-/-,-,- 2 022/222",
+		"S,-,-/-,-,-/-,-,- 2 222/222",
+		"-,-,-/-,-,-/-,-,- 1 222",
+	}
+	for _, position := range invalid {
+		_, err := ParsePosition(position)
+		assert.Error(t, err, "position %q must be rejected", position)
+	}
+}
@@ -0,0 +1,279 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	resultPlayer1Win = "1-0"
+	resultPlayer2Win = "0-1"
+	resultDraw       = "1/2-1/2"
+	resultUnfinished = "*"
+)
+
+var (
+	tagPattern        = regexp.MustCompile(`^\[(\w+) (".*")\]$`)
+	moveNumberPattern = regexp.MustCompile(`^\d+\.$`)
+)
+
+// GameRecord stores the course of a game. Moves are kept in the notation of MoveString,
+// so a record can be written and read without a board.
+type GameRecord struct {
+	Player1  string
+	Player2  string
+	Start    time.Time
+	End      time.Time
+	Rules    string // Rules of the game in the format of Rules.String, empty for the default rules
+	Position string // Starting position in the format of Board.Encode, empty for the standard start position
+	Moves    []string
+	Result   Outcome
+}
+
+// NewGameRecord creates a record for a game starting from the given board.
+func NewGameRecord(board *Board, player1, player2 string) *GameRecord {
+	record := &GameRecord{
+		Player1: player1,
+		Player2: player2,
+		Start:   time.Now(),
+	}
+	if board.Rules != DefaultRules {
+		record.Rules = board.Rules.String()
+	}
+	if position := board.Encode(); position != NewBoardWithRules(board.Rules).Encode() {
+		record.Position = position
+	}
+	return record
+}
+
+// AddMove appends a move to the record.
+func (r *GameRecord) AddMove(move Move) {
+	r.Moves = append(r.Moves, MoveString(move))
+}
+
+// Finish sets the result and end time of the record.
+func (r *GameRecord) Finish(outcome Outcome) {
+	r.Result = outcome
+	r.End = time.Now()
+}
+
+// Replay creates the starting board of the record and plays all recorded moves on it.
+func (r *GameRecord) Replay() (*Board, error) {
+	rules := DefaultRules
+	if r.Rules != "" {
+		var err error
+		if rules, err = ParseRules(r.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	board := NewBoardWithRules(rules)
+	if r.Position != "" {
+		var err error
+		board, err = ParsePositionWithRules(r.Position, rules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, input := range r.Moves {
+		move, err := ParseMove(input, board)
+		if err != nil {
+			return nil, fmt.Errorf("move %d %q: %w", i+1, input, err)
+		}
+		if err := board.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("move %d %q: %w", i+1, input, err)
+		}
+	}
+	return board, nil
+}
+
+// WriteRecord writes the record in a PGN-like text format: a block of tags followed by the numbered moves and the result.
+func WriteRecord(w io.Writer, r *GameRecord) error {
+	var sb strings.Builder
+	writeTag(&sb, "Player1", r.Player1)
+	writeTag(&sb, "Player2", r.Player2)
+	if !r.Start.IsZero() {
+		writeTag(&sb, "Start", r.Start.Format(time.RFC3339))
+	}
+	if !r.End.IsZero() {
+		writeTag(&sb, "End", r.End.Format(time.RFC3339))
+	}
+	if r.Rules != "" {
+		writeTag(&sb, "Rules", r.Rules)
+	}
+	if r.Position != "" {
+		writeTag(&sb, "Position", r.Position)
+	}
+	writeTag(&sb, "Result", resultString(r.Result))
+	sb.WriteByte('\n')
+
+	for i, move := range r.Moves {
+		if i%2 == 0 {
+			fmt.Fprintf(&sb, "%d. ", i/2+1)
+		}
+		sb.WriteString(move)
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(resultString(r.Result))
+	sb.WriteString("\n\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ReadRecords reads all records written by WriteRecord from the reader.
+func ReadRecords(reader io.Reader) ([]*GameRecord, error) {
+	var records []*GameRecord
+	var current *GameRecord
+	var movetext []string
+
+	finishRecord := func() error {
+		if current == nil {
+			return nil
+		}
+		if err := current.parseMovetext(strings.Join(movetext, " ")); err != nil {
+			return err
+		}
+		records = append(records, current)
+		current, movetext = nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			if len(movetext) > 0 {
+				if err := finishRecord(); err != nil {
+					return nil, err
+				}
+			}
+		case strings.HasPrefix(line, "["):
+			if len(movetext) > 0 {
+				if err := finishRecord(); err != nil {
+					return nil, err
+				}
+			}
+			if current == nil {
+				current = &GameRecord{}
+			}
+			if err := current.parseTag(line); err != nil {
+				return nil, err
+			}
+		default:
+			if current == nil {
+				current = &GameRecord{}
+			}
+			movetext = append(movetext, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finishRecord(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *GameRecord) parseTag(line string) error {
+	match := tagPattern.FindStringSubmatch(line)
+	if match == nil {
+		return fmt.Errorf("invalid tag %q", line)
+	}
+
+	value, err := strconv.Unquote(match[2])
+	if err != nil {
+		return fmt.Errorf("invalid tag value %s", match[2])
+	}
+
+	switch match[1] {
+	case "Player1":
+		r.Player1 = value
+	case "Player2":
+		r.Player2 = value
+	case "Start":
+		r.Start, err = time.Parse(time.RFC3339, value)
+	case "End":
+		r.End, err = time.Parse(time.RFC3339, value)
+	case "Rules":
+		r.Rules = value
+	case "Position":
+		r.Position = value
+	case "Result":
+		r.Result, err = parseResult(value)
+	}
+	return err
+}
+
+func (r *GameRecord) parseMovetext(movetext string) error {
+	var tokens []string
+	for _, token := range strings.Fields(movetext) {
+		if !moveNumberPattern.MatchString(token) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	// The movetext is terminated by the result
+	if len(tokens) == 0 {
+		return errors.New("movetext must end with the result")
+	}
+	result, err := parseResult(tokens[len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	if result != r.Result {
+		return fmt.Errorf("result %q does not match result tag", tokens[len(tokens)-1])
+	}
+	tokens = tokens[:len(tokens)-1]
+
+	// Every move consists of two tokens
+	if len(tokens)%2 != 0 {
+		return fmt.Errorf("incomplete move %q", tokens[len(tokens)-1])
+	}
+	r.Moves = nil
+	for i := 0; i < len(tokens); i += 2 {
+		r.Moves = append(r.Moves, tokens[i]+" "+tokens[i+1])
+	}
+	return nil
+}
+
+func writeTag(sb *strings.Builder, key, value string) {
+	fmt.Fprintf(sb, "[%s %q]\n", key, value)
+}
+
+func resultString(outcome Outcome) string {
+	switch outcome {
+	case Player1Wins:
+		return resultPlayer1Win
+	case Player2Wins:
+		return resultPlayer2Win
+	case Drawn:
+		return resultDraw
+	default:
+		return resultUnfinished
+	}
+}
+
+func parseResult(result string) (Outcome, error) {
+	switch result {
+	case resultPlayer1Win:
+		return Player1Wins, nil
+	case resultPlayer2Win:
+		return Player2Wins, nil
+	case resultDraw:
+		return Drawn, nil
+	case resultUnfinished:
+		return Undecided, nil
+	default:
+		return Undecided, fmt.Errorf("invalid result %q", result)
+	}
+}
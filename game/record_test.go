@@ -0,0 +1,84 @@
+package game
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	board := NewBoard()
+	record := NewGameRecord(board, "Human", "AI (depth 9)")
+	for _, move := range []Move{
+		NewMove(Player1, board.Get(0, 0), Small),
+		NewMove(Player2, board.Get(0, 0), Medium),
+		NewMove(Player1, board.Get(0, 0), Large),
+		NewMove(Player2, board.Get(1, 0), Small),
+		NewMove(Player1, board.Get(1, 0), Medium),
+		NewMove(Player2, board.Get(2, 2), Small),
+		NewMove(Player1, board.Get(2, 0), Small),
+	} {
+		board.MustMakeMove(move)
+		record.AddMove(move)
+	}
+	record.Finish(board.Outcome())
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteRecord(&buf, record))
+	assert.NoError(t, WriteRecord(&buf, &GameRecord{Player1: "A", Player2: "B", Position: board.Encode()}))
+	assert.NoError(t, WriteRecord(&buf, &GameRecord{Player1: "C", Player2: "D", Result: Drawn}))
+
+	records, err := ReadRecords(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(records), "all records must be read")
+
+	read := records[0]
+	assert.Equal(t, "Human", read.Player1)
+	assert.Equal(t, "AI (depth 9)", read.Player2)
+	assert.Equal(t, Player1Wins, read.Result)
+	assert.Equal(t, record.Moves, read.Moves)
+	assert.True(t, record.Start.Truncate(time.Second).Equal(read.Start), "start time must be preserved")
+
+	replayed, err := read.Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, board.Hash, replayed.Hash, "replayed board must match the original board")
+
+	assert.Equal(t, board.Encode(), records[1].Position)
+	assert.Empty(t, records[1].Moves)
+	assert.Equal(t, Undecided, records[1].Result)
+	assert.Equal(t, Drawn, records[2].Result)
+}
+
+func TestReadRecord(t *testing.T) {
+	input := `[Player1 "Alice"]
+[Player2 "Bob"]
+[Result "0-1"]
+
+1. a1 S a1 M 2. b2 S b2 M 3. c3 M c3 L
+0-1
+`
+	records, err := ReadRecords(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, []string{"a1 S", "a1 M", "b2 S", "b2 M", "c3 M", "c3 L"}, records[0].Moves)
+
+	board, err := records[0].Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, Player2, board.CheckWin(), "replayed game must be won by Player 2")
+}
+
+func TestReadRecordInvalid(t *testing.T) {
+	invalid := []string{
+		"[Result \"1-0\"]\n\na1 S 0-1\n",
+		"[Result \"*\"]\n\na1 S b2\n*\n",
+		"[Result \"*\"]\n\na1 S\n",
+		"[Start \"yesterday\"]\n\n*\n",
+	}
+	for _, input := range invalid {
+		_, err := ReadRecords(strings.NewReader(input))
+		assert.Error(t, err, "record %q must be rejected", input)
+	}
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxBoardSize  = 5
+	MaxPieceSizes = 4
+	// maxPiecesPerSize keeps the remaining piece counts of the position encoding single digits
+	maxPiecesPerSize = 9
+)
+
+// Rules configure the variant of the game that is played on a board
+type Rules struct {
+	BoardSize     int // Number of rows and columns of the grid
+	WinLength     int // Number of pieces in a row, column or diagonal needed to win
+	PieceSizes    int // Number of different piece sizes, starting with Small
+	PiecesPerSize int // Number of pieces of every size each player owns
+	// ExternalStacks keeps the pieces that are not on the board in PiecesPerSize nested stacks of all sizes,
+	// as in the original Gobblet. Only the top piece of a stack can be played, and a new piece may only gobble
+	// a piece of the opponent that is part of a line in which the opponent has all but one position.
+	ExternalStacks bool
+	// Uncover decides what happens when a player lifts a piece and uncovers a line of the opponent
+	Uncover UncoverRule
+}
+
+// UncoverRule decides what happens when a player lifts a piece and uncovers a line of the opponent
+type UncoverRule int
+
+const (
+	UncoverForbidden UncoverRule = iota // Moves that uncover a line of the opponent are illegal
+	UncoverLoses                        // Such moves are legal, but the opponent wins if their line is still complete after the move
+	UncoverOwnLine                      // Such moves are legal only if they complete a line of the mover, who then wins
+)
+
+var uncoverRuleNames = []string{"forbidden", "loses", "ownline"}
+
+func (u UncoverRule) String() string {
+	if u < 0 || int(u) >= len(uncoverRuleNames) {
+		return "unknown"
+	}
+	return uncoverRuleNames[u]
+}
+
+var (
+	// DefaultRules are the rules of Gobblet Gobblers
+	DefaultRules = Rules{BoardSize: 3, WinLength: 3, PieceSizes: 3, PiecesPerSize: 2}
+	// GobbletRules are the rules of the original Gobblet: a 4x4 grid with three external stacks of four sizes
+	GobbletRules = Rules{BoardSize: 4, WinLength: 4, PieceSizes: 4, PiecesPerSize: 3, ExternalStacks: true}
+)
+
+// variants maps the names accepted by ParseRules to their rules
+var variants = map[string]Rules{
+	"standard": DefaultRules,
+	"gobblet":  GobbletRules,
+}
+
+// Validate returns an error if a board can not be created with the rules
+func (r Rules) Validate() error {
+	if r.BoardSize < 2 || r.BoardSize > MaxBoardSize {
+		return fmt.Errorf("board size must be between 2 and %d, got %d", MaxBoardSize, r.BoardSize)
+	}
+	if r.WinLength < 2 || r.WinLength > r.BoardSize {
+		return fmt.Errorf("win length must be between 2 and the board size %d, got %d", r.BoardSize, r.WinLength)
+	}
+	if r.PieceSizes < 1 || r.PieceSizes > MaxPieceSizes {
+		return fmt.Errorf("number of piece sizes must be between 1 and %d, got %d", MaxPieceSizes, r.PieceSizes)
+	}
+	if r.PiecesPerSize < 1 || r.PiecesPerSize > maxPiecesPerSize {
+		return fmt.Errorf("pieces per size must be between 1 and %d, got %d", maxPiecesPerSize, r.PiecesPerSize)
+	}
+	return nil
+}
+
+// String returns the name of the variant, or the rules in the key=value format read by ParseRules
+func (r Rules) String() string {
+	for _, name := range []string{"standard", "gobblet"} {
+		if variants[name] == r {
+			return name
+		}
+	}
+	s := fmt.Sprintf("size=%d,win=%d,sizes=%d,pieces=%d", r.BoardSize, r.WinLength, r.PieceSizes, r.PiecesPerSize)
+	if r.ExternalStacks {
+		s += ",stacks=true"
+	}
+	if r.Uncover != UncoverForbidden {
+		s += ",uncover=" + r.Uncover.String()
+	}
+	return s
+}
+
+// ParseRules reads the name of a variant ("standard" or "gobblet") or a comma separated list of the options
+// size, win, sizes, pieces, stacks and uncover (forbidden, loses or ownline), e.g. "size=4,win=3,stacks=true".
+// Options that are not given keep their default value.
+func ParseRules(s string) (Rules, error) {
+	if rules, found := variants[strings.TrimSpace(s)]; found {
+		return rules, nil
+	}
+
+	rules := DefaultRules
+	for _, option := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			return rules, fmt.Errorf("unknown variant %q", option)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "stacks" {
+			stacks, err := strconv.ParseBool(value)
+			if err != nil {
+				return rules, fmt.Errorf("invalid value of rule %s: %w", key, err)
+			}
+			rules.ExternalStacks = stacks
+			continue
+		}
+		if key == "uncover" {
+			uncover := slices.Index(uncoverRuleNames, value)
+			if uncover < 0 {
+				return rules, fmt.Errorf("invalid value of rule %s: must be one of %s", key, strings.Join(uncoverRuleNames, ", "))
+			}
+			rules.Uncover = UncoverRule(uncover)
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return rules, fmt.Errorf("invalid value of rule %s: %w", key, err)
+		}
+		switch key {
+		case "size":
+			rules.BoardSize = number
+		case "win":
+			rules.WinLength = number
+		case "sizes":
+			rules.PieceSizes = number
+		case "pieces":
+			rules.PiecesPerSize = number
+		default:
+			return rules, fmt.Errorf("unknown rule %q", key)
+		}
+	}
+	return rules, rules.Validate()
+}
+
+// Sizes returns all piece sizes from smallest to largest
+func (r Rules) Sizes() []Size {
+	sizes := make([]Size, r.PieceSizes)
+	for i := range sizes {
+		sizes[i] = Size(i)
+	}
+	return sizes
+}
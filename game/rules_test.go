@@ -0,0 +1,166 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("gobblet")
+	assert.NoError(t, err)
+	assert.Equal(t, GobbletRules, rules)
+
+	rules, err = ParseRules("size=4,win=3")
+	assert.NoError(t, err)
+	assert.Equal(t, Rules{BoardSize: 4, WinLength: 3, PieceSizes: 3, PiecesPerSize: 2}, rules)
+	assert.Equal(t, "size=4,win=3,sizes=3,pieces=2", rules.String())
+	assert.Equal(t, "standard", DefaultRules.String())
+
+	rules, err = ParseRules("size=4,win=4,sizes=4,pieces=3")
+	assert.NoError(t, err)
+	assert.Equal(t, GobbletRules, Rules{BoardSize: 4, WinLength: 4, PieceSizes: 4, PiecesPerSize: 3, ExternalStacks: true})
+	assert.NotEqual(t, GobbletRules, rules, "external stacks must be disabled by default")
+	rules, err = ParseRules(rules.String() + ",stacks=true")
+	assert.NoError(t, err)
+	assert.Equal(t, GobbletRules, rules)
+
+	rules, err = ParseRules("uncover=ownline")
+	assert.NoError(t, err)
+	assert.Equal(t, UncoverOwnLine, rules.Uncover)
+	assert.Equal(t, "size=3,win=3,sizes=3,pieces=2,uncover=ownline", rules.String())
+
+	for _, invalid := range []string{"chess", "size=6", "size=3,win=4", "sizes=5", "pieces=0", "depth=2", "stacks=maybe", "uncover=wins"} {
+		_, err = ParseRules(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLines(t *testing.T) {
+	assert.Len(t, NewBoard().Lines, 8)
+	assert.Len(t, NewBoardWithRules(GobbletRules).Lines, 4+4+2)
+	// Every row and column of 4 positions has 2 segments of length 3, both diagonal directions have 4 segments
+	assert.Len(t, NewBoardWithRules(Rules{BoardSize: 4, WinLength: 3, PieceSizes: 3, PiecesPerSize: 2}).Lines, 8+8+4+4)
+}
+
+func TestGobbletWin(t *testing.T) {
+	board := NewBoardWithRules(GobbletRules)
+	for col := 0; col < 3; col++ {
+		board.MustMakeMove(NewMove(Player1, board.Get(col, col), ExtraLarge))
+		assert.Equal(t, None, board.CheckWin(), "three pieces must not win with win length 4")
+		board.MustMakeMove(NewMove(Player2, board.Get(3, col), ExtraLarge))
+	}
+	board.MustMakeMove(NewMove(Player1, board.Get(3, 3), Large))
+	assert.Equal(t, Player1, board.CheckWin(), "four pieces on the diagonal must win")
+}
+
+func TestGobbletEncoding(t *testing.T) {
+	board := NewBoardWithRules(GobbletRules)
+	assert.Equal(t, "-,-,-,-/-,-,-,-/-,-,-,-/-,-,-,- 1 3333/3333", board.Encode())
+
+	move, err := ParseMove("d4 X", board)
+	assert.NoError(t, err)
+	board.MustMakeMove(move)
+	move, err = ParseMove("d4 x", board)
+	assert.NoError(t, err, "size letters must be case insensitive")
+	assert.Error(t, board.MakeMove(move), "extra large piece must not gobble an extra large piece")
+
+	parsed, err := ParsePositionWithRules(board.Encode(), GobbletRules)
+	assert.NoError(t, err)
+	assert.Equal(t, board.Hash, parsed.Hash)
+	assert.Equal(t, "-,-,-,-/-,-,-,-/-,-,-,-/-,-,-,X 2 3332/3333", parsed.Encode())
+
+	_, err = ParsePosition(board.Encode())
+	assert.Error(t, err, "position must not be valid with the default rules")
+	_, err = ParseMove("d4 S", NewBoard())
+	assert.Error(t, err, "position must be on the board")
+	_, err = ParseMove("a1 X", NewBoard())
+	assert.Error(t, err, "size must be part of the rules")
+}
+
+func TestGobbletSymmetries(t *testing.T) {
+	board := NewBoardWithRules(GobbletRules)
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 1), ExtraLarge))
+	hash, _ := board.CanonicalHash()
+
+	rotated := NewBoardWithRules(GobbletRules)
+	row, col := Rotate90.Transform(0, 1, GobbletRules.BoardSize)
+	rotated.MustMakeMove(NewMove(Player1, rotated.Get(row, col), ExtraLarge))
+	rotatedHash, _ := rotated.CanonicalHash()
+	assert.Equal(t, hash, rotatedHash, "rotated positions must have the same canonical hash")
+	assert.NotEqual(t, board.Hash, rotated.Hash)
+}
+
+func TestExternalStacks(t *testing.T) {
+	board := NewBoardWithRules(GobbletRules)
+	assert.Equal(t, []Size{ExtraLarge}, board.AvailablePieceSizes(Player1), "only the top pieces of the stacks must be playable")
+	assert.Equal(t, []int{4, 4, 4}, board.StackHeights(Player1))
+
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 0), ExtraLarge))
+	assert.Equal(t, []Size{Large, ExtraLarge}, board.AvailablePieceSizes(Player1))
+	assert.Equal(t, []int{4, 4, 3}, board.StackHeights(Player1))
+
+	_, err := ParsePositionWithRules("S,-,-,-/-,-,-,-/-,-,-,-/-,-,-,- 2 2333/3333", GobbletRules)
+	assert.Error(t, err, "small pieces must not be played before the larger pieces of their stack")
+}
+
+func TestExternalStacksGobbling(t *testing.T) {
+	board, err := ParsePositionWithRules("m,-,-,-/-,l,-,-/-,-,x,-/L,-,-,X 1 3322/3222", GobbletRules)
+	assert.NoError(t, err)
+
+	// The medium piece of Player 2 on a1 is part of a diagonal with three pieces of Player 2
+	assert.NoError(t, board.MakeMove(NewMove(Player1, board.Get(0, 0), ExtraLarge)), "threatening pieces must be gobbled from the stacks")
+	board.MustUndoMove(NewMove(Player1, board.Get(0, 0), ExtraLarge))
+
+	valid, _ := board.IsValidMove(NewMove(Player1, board.Get(3, 0), ExtraLarge))
+	assert.False(t, valid, "own pieces must not be gobbled from the stacks")
+
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 1), ExtraLarge))
+	board.MustMakeMove(NewMoveExisting(board.Get(0, 0), board.Get(0, 2)))
+	valid, _ = board.IsValidMove(NewMove(Player1, board.Get(0, 2), Large))
+	assert.False(t, valid, "pieces without a threat must not be gobbled from the stacks")
+	assert.NoError(t, board.MakeMove(NewMoveExisting(board.Get(0, 1), board.Get(0, 2))), "pieces on the board may gobble any smaller piece")
+}
+
+func TestUncoverRules(t *testing.T) {
+	// Lifting the large piece on a1 uncovers the first row of Player 2, moving it to c2 completes the second row of Player 1
+	position := "sL,m,s/M,M,-/-,-,- 1 201/012"
+	tests := []struct {
+		uncover         UncoverRule
+		validCompleting bool
+		validOther      bool
+		winner          Player // Winner after the completing move
+	}{
+		{UncoverForbidden, false, false, None},
+		{UncoverLoses, true, true, Player2},
+		{UncoverOwnLine, true, false, Player1},
+	}
+
+	for _, test := range tests {
+		rules := DefaultRules
+		rules.Uncover = test.uncover
+		board, err := ParsePositionWithRules(position, rules)
+		assert.NoError(t, err)
+
+		completing := NewMoveExisting(board.Get(0, 0), board.Get(1, 2))
+		other := NewMoveExisting(board.Get(0, 0), board.Get(2, 2))
+		valid, _ := board.IsValidMove(completing)
+		assert.Equal(t, test.validCompleting, valid, test.uncover)
+		valid, _ = board.IsValidMove(other)
+		assert.Equal(t, test.validOther, valid, test.uncover)
+		assert.Equal(t, test.validOther, slices.ContainsFunc(board.GetPossibleMoves(), func(move Move) bool {
+			return move.From == other.From && move.To == other.To
+		}), "move generation must agree with IsValidMove")
+
+		if test.validOther {
+			board.MustMakeMove(other)
+			assert.Equal(t, Player2, board.CheckWin(), "the uncovered line of the opponent must win")
+			board.MustUndoMove(other)
+		}
+		if test.validCompleting {
+			board.MustMakeMove(completing)
+			assert.Equal(t, test.winner, board.CheckWin(), test.uncover)
+		}
+	}
+}
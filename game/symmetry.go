@@ -0,0 +1,79 @@
+package game
+
+// Symmetry is one of the 8 rotations and reflections of the board.
+type Symmetry int
+
+const (
+	Identity Symmetry = iota
+	Rotate90
+	Rotate180
+	Rotate270
+	FlipHorizontal
+	FlipVertical
+	FlipDiagonal
+	FlipAntiDiagonal
+)
+
+const symmetryCount = 8
+
+// Transform returns the coordinates a position of a board with the given size is mapped to by the symmetry.
+func (s Symmetry) Transform(row, col, size int) (int, int) {
+	last := size - 1
+	switch s {
+	case Identity:
+		return row, col
+	case Rotate90:
+		return col, last - row
+	case Rotate180:
+		return last - row, last - col
+	case Rotate270:
+		return last - col, row
+	case FlipHorizontal:
+		return row, last - col
+	case FlipVertical:
+		return last - row, col
+	case FlipDiagonal:
+		return col, row
+	case FlipAntiDiagonal:
+		return last - col, last - row
+	default:
+		panic("invalid symmetry")
+	}
+}
+
+// Inverse returns the symmetry that reverts the transformation of s.
+func (s Symmetry) Inverse() Symmetry {
+	switch s {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	default:
+		return s // All other symmetries are their own inverse
+	}
+}
+
+// CanonicalHash returns the smallest hash of all symmetric variants of the board
+// together with the symmetry that transforms the board into the variant with that hash.
+func (b *Board) CanonicalHash() (hash uint64, symmetry Symmetry) {
+	hash, symmetry = b.symmetryHashes[Identity], Identity
+	for s := Symmetry(1); s < symmetryCount; s++ {
+		if b.symmetryHashes[s] < hash {
+			hash, symmetry = b.symmetryHashes[s], s
+		}
+	}
+	return hash, symmetry
+}
+
+// TransformMove maps the positions of a move with the given symmetry onto this board.
+// Moves without a target position are returned unchanged.
+func (b *Board) TransformMove(move Move, s Symmetry) Move {
+	if move.To == nil {
+		return move
+	}
+	move.To = b.Get(s.Transform(move.To.Row, move.To.Col, b.Rules.BoardSize))
+	if move.From != nil {
+		move.From = b.Get(s.Transform(move.From.Row, move.From.Col, b.Rules.BoardSize))
+	}
+	return move
+}
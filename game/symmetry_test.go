@@ -0,0 +1,66 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalHashOfSymmetricBoards(t *testing.T) {
+	moves := [][2]int{{0, 0}, {0, 1}, {1, 2}}
+	var hashes []uint64
+
+	for s := Identity; s < symmetryCount; s++ {
+		board := NewBoard()
+		for i, coords := range moves {
+			row, col := s.Transform(coords[0], coords[1], DefaultRules.BoardSize)
+			board.MustMakeMove(NewMove(board.ActivePlayer, board.Get(row, col), Size(i)))
+		}
+		hash, symmetry := board.CanonicalHash()
+		assert.Equal(t, board.symmetryHashes[symmetry], hash, "canonical hash must be the hash of the returned symmetry")
+		hashes = append(hashes, hash)
+	}
+
+	for _, hash := range hashes {
+		assert.Equal(t, hashes[0], hash, "symmetric boards must have the same canonical hash")
+	}
+}
+
+func TestCanonicalHashDiffers(t *testing.T) {
+	board1 := NewBoard()
+	board1.MustMakeMove(NewMove(Player1, board1.Get(0, 0), Small))
+	board2 := NewBoard()
+	board2.MustMakeMove(NewMove(Player1, board2.Get(0, 1), Small))
+
+	hash1, _ := board1.CanonicalHash()
+	hash2, _ := board2.CanonicalHash()
+	assert.NotEqual(t, hash1, hash2, "corner and edge placements must have different canonical hashes")
+}
+
+func TestSymmetryHashesAfterUndo(t *testing.T) {
+	board := NewBoard()
+	board.MustMakeMove(NewMove(Player1, board.Get(0, 0), Medium))
+	board.MustMakeMove(NewMove(Player2, board.Get(1, 2), Small))
+	hashes := board.symmetryHashes
+
+	move := NewMoveExisting(board.Get(0, 0), board.Get(1, 2))
+	board.MustMakeMove(move)
+	board.MustUndoMove(move)
+	assert.Equal(t, hashes, board.symmetryHashes, "symmetry hashes must be equal after UndoMove")
+	assert.Equal(t, board.Hash, board.symmetryHashes[Identity], "identity hash must be equal to the board hash")
+}
+
+func TestTransformMove(t *testing.T) {
+	board := NewBoard()
+	move := NewMoveExisting(board.Get(0, 1), board.Get(2, 2))
+
+	for s := Identity; s < symmetryCount; s++ {
+		transformed := board.TransformMove(move, s)
+		assert.Equal(t, move, board.TransformMove(transformed, s.Inverse()), "inverse symmetry must restore the move")
+	}
+
+	rotated := board.TransformMove(move, Rotate90)
+	assert.Equal(t, board.Get(1, 2), rotated.From)
+	assert.Equal(t, board.Get(2, 0), rotated.To)
+	assert.Equal(t, Move{}, board.TransformMove(Move{}, Rotate90), "empty move must stay empty")
+}
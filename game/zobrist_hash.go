@@ -1,23 +1,28 @@
 package game
 
 import (
+	"hash/fnv"
 	"math/rand"
 )
 
-var zobristTable [3][3][6]uint64 // 3x3 board, 6 unique pieces
-var activePlayerHash [3]uint64   // 1 random value per player
+// zobristSeed is fixed so that hashes are stable across runs and can be persisted, e.g. in a tablebase
+const zobristSeed = 0x6f62626c6574
+
+var zobristTable [MaxBoardSize][MaxBoardSize][2 * MaxPieceSizes]uint64 // Largest board, every piece of both players
+var activePlayerHash [3]uint64                                         // 1 random value per player
 
 // InitZobrist initializes the hash table
 func InitZobrist() {
-	for row := 0; row < 3; row++ {
-		for col := 0; col < 3; col++ {
-			for piece := 0; piece < 6; piece++ {
-				zobristTable[row][col][piece] = rand.Uint64()
+	random := rand.New(rand.NewSource(zobristSeed))
+	for row := 0; row < MaxBoardSize; row++ {
+		for col := 0; col < MaxBoardSize; col++ {
+			for piece := 0; piece < 2*MaxPieceSizes; piece++ {
+				zobristTable[row][col][piece] = random.Uint64()
 			}
 		}
 	}
-	activePlayerHash[1] = rand.Uint64() // Player 1 hash
-	activePlayerHash[2] = rand.Uint64() // Player 2 hash
+	activePlayerHash[1] = random.Uint64() // Player 1 hash
+	activePlayerHash[2] = random.Uint64() // Player 2 hash
 }
 
 func init() {
@@ -31,3 +36,15 @@ func GetZobristValue(position *Position, piece Piece) uint64 {
 func GetPlayerZobristValue(activePlayer Player) uint64 {
 	return activePlayerHash[activePlayer]
 }
+
+// GetRulesZobristValue returns a value derived from the rules that is part of the hash of every board,
+// so positions of different variants do not share their hashes in transposition tables, tablebases and opening books
+func GetRulesZobristValue(rules Rules) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(rules.String()))
+	// Mix the bits with the finalizer of SplitMix64, FNV alone changes few bits for similar rules
+	x := h.Sum64()
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
@@ -9,7 +9,8 @@ import (
 func TestZobristHash(t *testing.T) {
 	board := NewBoard()
 	hash1 := board.Hash
-	assert.Equal(t, GetPlayerZobristValue(Player1), hash1, "hash must be equal to player 1 hash")
+	assert.Equal(t, GetPlayerZobristValue(Player1)^GetRulesZobristValue(DefaultRules), hash1,
+		"hash must be equal to player 1 hash combined with the hash of the rules")
 
 	move := NewMove(Player1, board.Get(0, 0), Small)
 	board.MustMakeMove(move)
@@ -39,3 +40,13 @@ func TestZobristHashMovePiece(t *testing.T) {
 
 	assert.NotEqual(t, hash1, hash2, "hash must be different for different game states")
 }
+
+func TestZobristHashRules(t *testing.T) {
+	standard := NewBoardWithRules(variants["standard"])
+	gobblet := NewBoardWithRules(variants["gobblet"])
+	assert.NotEqual(t, standard.Hash, gobblet.Hash, "the start positions of different rules must have different hashes")
+
+	uncover := variants["standard"]
+	uncover.Uncover = UncoverLoses
+	assert.NotEqual(t, standard.Hash, NewBoardWithRules(uncover).Hash, "rules with the same board must have different hashes")
+}
@@ -6,6 +6,7 @@ toolchain go1.24.6
 
 require (
 	github.com/fatih/color v1.18.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/stretchr/testify v1.11.1
 )
 
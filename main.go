@@ -1,24 +1,277 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
 
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
 	"gibhub.com/bef1993/gobblet-gobblers/cli"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"gibhub.com/bef1993/gobblet-gobblers/protocol"
+	"gibhub.com/bef1993/gobblet-gobblers/server"
+	"gibhub.com/bef1993/gobblet-gobblers/tournament"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "solve":
+			solve(os.Args[2:])
+			return
+		case "book":
+			book(os.Args[2:])
+			return
+		case "tournament":
+			runTournament(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "gobblet-engine":
+			runEngine(os.Args[2:])
+			return
+		}
+	}
+
 	maxDepth := flag.Int("maxDepth", 9, "the maximum search depth for the AI")
+	moveTime := flag.Duration("moveTime", 0, "the time the AI may think per move, e.g. 5s (searches up to maxDepth with iterative deepening)")
+	tablebase := flag.String("tablebase", "", "load a tablebase created with the solve command for perfect play")
+	openingBook := flag.String("book", "", "load an opening book created with the book command")
+	engine := flag.String("engine", "minimax", fmt.Sprintf("the AI engine with options, e.g. minimax:depth=7 or mcts:iterations=50000 (engines: %s)", strings.Join(ai.EngineNames(), ", ")))
+	rulesFlag := flag.String("rules", "standard", "the rule variant: standard, gobblet or options like size=4,win=3,sizes=3,pieces=2")
+	mode := flag.String("mode", "human-ai", "the game mode: human-ai, human-human or ai-ai")
+	engine1 := flag.String("engine1", "", "the engine of Player 1 in ai-ai mode (defaults to -engine)")
+	engine2 := flag.String("engine2", "", "the engine of Player 2 in ai-ai mode (defaults to -engine)")
+	maxPlies := flag.Int("maxPlies", 200, "draw games that are not decided after this many plies (0 for no limit)")
+	save := flag.String("save", "", "append the record of the game to this file")
+	replay := flag.String("replay", "", "replay all games recorded in this file and exit")
 	flag.Parse()
 
+	if *replay != "" {
+		if err := cli.ReplayRecords(*replay); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	rules := parseRules(*rulesFlag)
+	defaults := ai.Limits{MaxDepth: *maxDepth, MoveTime: *moveTime}
+	usesMinimax := false
+	newAI := func(engineSpec string) cli.Participant {
+		spec, err := ai.ParseEngineSpec(engineSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// -tablebase and -book are shortcuts for the options of the minimax engine
+		if spec.Name == "minimax" {
+			usesMinimax = true
+			if *tablebase != "" {
+				spec.Options["tablebase"] = *tablebase
+			}
+			if *openingBook != "" {
+				spec.Options["book"] = *openingBook
+			}
+		}
+		engine, limits, err := ai.NewEngine(spec, rules, defaults)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return cli.AI(spec.Name, engine, limits)
+	}
+
 	fmt.Println("Welcome to Gobblet Gobblers")
-	fmt.Println("Do you want to play as Player 1 or Player 2?")
-	player, err := cli.DetermineHumanPlayer()
+	var player1, player2 cli.Participant
+	switch *mode {
+	case "human-ai":
+		fmt.Println("Do you want to play as Player 1 or Player 2?")
+		player, err := cli.DetermineHumanPlayer()
+		if err != nil {
+			log.Fatal(err)
+		}
+		player1, player2 = cli.Human(), newAI(*engine)
+		if player == game.Player2 {
+			player1, player2 = player2, player1
+		}
+	case "human-human":
+		player1, player2 = cli.Human(), cli.Human()
+	case "ai-ai":
+		player1, player2 = newAI(cmp.Or(*engine1, *engine)), newAI(cmp.Or(*engine2, *engine))
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+	if (*tablebase != "" || *openingBook != "") && !usesMinimax {
+		log.Fatal("-tablebase and -book can only be used with the minimax engine")
+	}
+
+	record := cli.PlayGame(player1, player2, rules, *maxPlies)
+	if *save != "" {
+		if err := cli.SaveRecord(*save, record); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func solve(args []string) {
+	flags := flag.NewFlagSet("solve", flag.ExitOnError)
+	position := flags.String("position", "", "the encoded position to solve (default: the start position)")
+	rulesFlag := flags.String("rules", "standard", "the rule variant of the position")
+	maxPositions := flags.Int("maxPositions", ai.DefaultMaxPositions, "give up if more positions are reachable")
+	out := flags.String("out", "tablebase.bin", "the file to write the tablebase to")
+	_ = flags.Parse(args)
+
+	if err := cli.SolvePosition(*position, parseRules(*rulesFlag), *maxPositions, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func book(args []string) {
+	flags := flag.NewFlagSet("book", flag.ExitOnError)
+	plies := flags.Int("plies", 2, "the number of plies from the start position covered by the book")
+	maxDepth := flags.Int("maxDepth", 9, "the maximum search depth for every book position")
+	moveTime := flags.Duration("moveTime", 0, "the time to search every book position (searches up to maxDepth with iterative deepening)")
+	out := flags.String("out", "book.txt", "the file to write the opening book to")
+	rulesFlag := flags.String("rules", "standard", "the rule variant of the book")
+	_ = flags.Parse(args)
+
+	limits := ai.Limits{MaxDepth: *maxDepth, MoveTime: *moveTime}
+	if err := cli.GenerateOpeningBook(*plies, parseRules(*rulesFlag), limits, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// engineList collects the values of a flag that can be repeated
+type engineList []string
+
+func (e *engineList) String() string {
+	return strings.Join(*e, " ")
+}
+
+func (e *engineList) Set(value string) error {
+	*e = append(*e, value)
+	return nil
+}
+
+func runTournament(args []string) {
+	flags := flag.NewFlagSet("tournament", flag.ExitOnError)
+	var engines engineList
+	flags.Var(&engines, "engine", "an engine of the tournament, e.g. minimax:depth=5 (repeat for every engine)")
+	format := flags.String("format", "roundrobin", "the tournament format: roundrobin or gauntlet (the first engine against all others)")
+	openings := flags.String("openings", "", "a file with one encoded start position per line")
+	openingPlies := flags.Int("openingPlies", 0, "start from all distinct positions after this many plies instead of -openings")
+	rounds := flags.Int("rounds", 1, "the number of times every pairing plays every opening with both colors")
+	maxDepth := flags.Int("maxDepth", 5, "the default maximum search depth of the engines")
+	moveTime := flags.Duration("moveTime", 0, "the default time the engines may think per move")
+	maxPlies := flags.Int("maxPlies", tournament.DefaultMaxPlies, "draw games that are not decided after this many plies")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "the number of games played in parallel")
+	save := flags.String("save", "", "append the records of all games to this file")
+	rulesFlag := flags.String("rules", "standard", "the rule variant of all games")
+	_ = flags.Parse(args)
+
+	config := tournament.Config{
+		Rules:       parseRules(*rulesFlag),
+		Rounds:      *rounds,
+		Limits:      ai.Limits{MaxDepth: *maxDepth, MoveTime: *moveTime},
+		MaxPlies:    *maxPlies,
+		Concurrency: *concurrency,
+	}
+	for _, engine := range engines {
+		spec, err := ai.ParseEngineSpec(engine)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Engines = append(config.Engines, spec)
+	}
+
+	switch *format {
+	case "roundrobin":
+		config.Format = tournament.RoundRobin
+	case "gauntlet":
+		config.Format = tournament.Gauntlet
+	default:
+		log.Fatalf("unknown tournament format %q", *format)
+	}
+
+	if *openingPlies > 0 {
+		config.Openings = tournament.Openings(config.Rules, *openingPlies)
+	} else if *openings != "" {
+		file, err := os.Open(*openings)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Openings, err = tournament.ReadOpenings(file, config.Rules)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := cli.RunTournament(config, *save); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "the address to listen on")
+	engine := flags.String("engine", "minimax", "the engine that plays the engine moves, with options")
+	maxDepth := flags.Int("maxDepth", 9, "the default maximum search depth of engine moves")
+	moveTime := flags.Duration("moveTime", 0, "the default time the engine may think per move")
+	limitDepth := flags.Int("limitDepth", 12, "the maximum search depth clients may request (0 for no limit)")
+	limitTime := flags.Duration("limitTime", 10*time.Second, "the maximum time of an engine move (0 for the default of 10s)")
+	maxPlies := flags.Int("maxPlies", server.DefaultMaxPlies, "draw games that are not decided after this many plies, unless set when creating a game")
+	maxGames := flags.Int("maxGames", server.DefaultMaxGames, "the maximum number of games held at the same time")
+	idleTimeout := flags.Duration("idleTimeout", server.DefaultIdleTimeout, "remove games that were not used for this long")
+	_ = flags.Parse(args)
+
+	spec, err := ai.ParseEngineSpec(*engine)
 	if err != nil {
 		log.Fatal(err)
-		return
 	}
+	s, err := server.New(server.Config{
+		Engine:      spec,
+		Limits:      ai.Limits{MaxDepth: *maxDepth, MoveTime: *moveTime},
+		MaxLimits:   ai.Limits{MaxDepth: *limitDepth, MoveTime: *limitTime},
+		MaxPlies:    *maxPlies,
+		MaxGames:    *maxGames,
+		IdleTimeout: *idleTimeout,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
+
+func runEngine(args []string) {
+	flags := flag.NewFlagSet("gobblet-engine", flag.ExitOnError)
+	engine := flags.String("engine", "minimax", "the engine that answers the commands, with options")
+	maxDepth := flags.Int("maxDepth", 9, "the maximum search depth of go commands without limits")
+	moveTime := flags.Duration("moveTime", 0, "the time to search for go commands without limits")
+	_ = flags.Parse(args)
+
+	spec, err := ai.ParseEngineSpec(*engine)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := protocol.Config{Engine: spec, Limits: ai.Limits{MaxDepth: *maxDepth, MoveTime: *moveTime}}
+	if err := protocol.Serve(context.Background(), os.Stdin, os.Stdout, config); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	cli.PlayGame(player, *maxDepth)
+func parseRules(variant string) game.Rules {
+	rules, err := game.ParseRules(variant)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return rules
 }
@@ -0,0 +1,251 @@
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// handshakeTimeout is the time an engine has to answer the gobblet command
+const handshakeTimeout = 10 * time.Second
+
+func init() {
+	ai.RegisterEngine("external", newExternalEngine)
+}
+
+// newExternalEngine starts the program given by the option command, e.g. external:command=./gobblet_gobblers gobblet-engine
+func newExternalEngine(options *ai.EngineOptions) (ai.Engine, error) {
+	command := strings.Fields(options.String("command", ""))
+	if len(command) == 0 {
+		return nil, errors.New("option command is required")
+	}
+	if options.Validating() {
+		_, err := exec.LookPath(command[0])
+		return nil, err
+	}
+	return StartClient(command[0], command[1:]...)
+}
+
+// Client is an ai.Engine that lets an engine speaking the protocol search, e.g. a program written by others.
+// Like all engines, a Client must not be used by multiple goroutines at the same time.
+type Client struct {
+	Name string // Name the engine sent with "id name"
+
+	out     io.Writer
+	outErr  error
+	lines   chan string // Lines written by the engine, closed when its output ends
+	rules   *game.Rules // Rules of the last newgame command, nil before the first search
+	cmd     *exec.Cmd   // Process of the engine if started by StartClient
+	stdin   io.Closer
+	closeMu sync.Once
+}
+
+// NewClient connects to an engine that reads the commands written to out and answers on in
+func NewClient(in io.Reader, out io.Writer) (*Client, error) {
+	c := &Client{out: out, lines: make(chan string, 64)}
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+
+	c.send("gobblet")
+	timeout := time.After(handshakeTimeout)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return nil, errors.New("engine exited before answering gobblet")
+			}
+			if name, found := strings.CutPrefix(line, "id name "); found {
+				c.Name = name
+			}
+			if line == "gobbletok" {
+				return c, c.outErr
+			}
+		case <-timeout:
+			return nil, errors.New("engine did not answer gobblet in time")
+		}
+	}
+}
+
+// StartClient starts the program of an engine and connects to its standard input and output
+func StartClient(name string, args ...string) (*Client, error) {
+	cmd := exec.Command(name, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(stdout, stdin)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	c.cmd, c.stdin = cmd, stdin
+	return c, nil
+}
+
+// Close asks the engine to quit and waits for its process to exit if it was started by StartClient
+func (c *Client) Close() error {
+	var err error
+	c.closeMu.Do(func() {
+		c.send("quit")
+		if c.cmd != nil {
+			_ = c.stdin.Close()
+			err = c.cmd.Wait()
+		}
+	})
+	return err
+}
+
+// NewGame sends newgame before the next search, so the engine discards the knowledge of previous games
+func (c *Client) NewGame() {
+	c.rules = nil
+}
+
+// Search sends the position of the board to the engine and waits for its best move.
+// The engine is asked to stop when ctx is done. The repetition history of the board is not sent.
+func (c *Client) Search(ctx context.Context, board *game.Board, limits ai.Limits) (ai.SearchResult, error) {
+	if c.rules == nil || *c.rules != board.Rules {
+		rules := board.Rules
+		c.send("newgame " + rules.String())
+		c.rules = &rules
+	}
+	c.send("position " + board.Encode())
+	command := "go"
+	if limits.MaxDepth > 0 {
+		command += " depth " + strconv.Itoa(limits.MaxDepth)
+	}
+	if limits.MoveTime > 0 {
+		command += " movetime " + strconv.FormatInt(limits.MoveTime.Milliseconds(), 10)
+	}
+	c.send(command)
+	if c.outErr != nil {
+		return ai.SearchResult{}, c.outErr
+	}
+
+	start := time.Now()
+	result := ai.SearchResult{}
+	var lastError string
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			c.send("stop")
+			done = nil
+		case line, ok := <-c.lines:
+			if !ok {
+				return result, errors.New("engine exited during the search")
+			}
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "info":
+				if fields[1] == "string" {
+					lastError = strings.Join(fields[2:], " ")
+					continue
+				}
+				parseInfo(fields[1:], board, &result)
+				iteration := ai.Iteration{Depth: result.Depth, Evaluation: result.Evaluation, PV: result.PV,
+					Nodes: result.Nodes, Elapsed: time.Since(start)}
+				if len(result.PV) > 0 {
+					iteration.BestMove = result.PV[0]
+				}
+				result.Iterations = append(result.Iterations, iteration)
+				if limits.OnIteration != nil {
+					limits.OnIteration(iteration)
+				}
+			case "bestmove":
+				result.Elapsed = time.Since(start)
+				if fields[1] == "none" {
+					return result, ctx.Err()
+				}
+				move, err := ParseMove(fields[1], board)
+				if err == nil {
+					_, err = board.IsValidMove(move)
+				}
+				if err != nil {
+					if lastError != "" {
+						err = fmt.Errorf("%w (engine: %s)", err, lastError)
+					}
+					return result, fmt.Errorf("invalid best move %s: %w", fields[1], err)
+				}
+				result.BestMove = move
+				return result, ctx.Err()
+			}
+		}
+	}
+}
+
+// parseInfo reads the depth, score, nodes and pv of an info line into the result
+func parseInfo(fields []string, board *game.Board, result *ai.SearchResult) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		value, _ := strconv.Atoi(fields[i+1])
+		switch fields[i] {
+		case "depth":
+			result.Depth = value
+		case "score":
+			if i+2 < len(fields) && (fields[i+1] == "win" || fields[i+1] == "loss") {
+				i++
+				value, _ = strconv.Atoi(fields[i+1])
+				if fields[i] == "loss" {
+					value = ai.WinScore(game.Player2, value)
+				} else {
+					value = ai.WinScore(game.Player1, value)
+				}
+			}
+			result.Evaluation = value
+			result.WinIn = ai.WinIn(value)
+		case "nodes":
+			result.Nodes = value
+		case "pv":
+			result.PV = parsePV(fields[i+1:], board)
+			return
+		}
+	}
+}
+
+// parsePV parses the moves of a principal variation until the first invalid move
+func parsePV(tokens []string, board *game.Board) []game.Move {
+	var pv []game.Move
+	for _, token := range tokens {
+		move, err := ParseMove(token, board)
+		if err != nil || board.MakeMove(move) != nil {
+			break
+		}
+		pv = append(pv, move)
+	}
+	for i := len(pv) - 1; i >= 0; i-- {
+		board.MustUndoMove(pv[i])
+	}
+	return pv
+}
+
+func (c *Client) send(line string) {
+	if c.outErr == nil {
+		_, c.outErr = fmt.Fprintln(c.out, line)
+	}
+}
@@ -0,0 +1,301 @@
+// Package protocol implements a line based text protocol between engines and the programs that use them, similar to UCI.
+//
+// Commands sent to the engine:
+//
+//	gobblet                                  identify the engine, answered with id lines and gobbletok
+//	isready                                  answered with readyok once all previous commands are handled
+//	setoption name <key> value <value>       set an option of the engine spec, or the engine itself with the key engine
+//	newgame [rules]                          start a new game with the rules read by game.ParseRules, standard if omitted
+//	position startpos|<encoded> [moves ...]  set up the start position or an encoded position and play the moves
+//	go [depth <plies>] [movetime <ms>]       search the position, answered with info lines and bestmove
+//	stop                                     stop the search as soon as possible
+//	quit                                     exit
+//
+// Moves are written without spaces, e.g. b2S for placing a small piece on b2 and a1c3 for moving a piece.
+// The engine answers go with a line like "info depth 5 score 12 nodes 1234 time 56 pv b2L a1S" after every completed
+// iteration of the search, followed by "bestmove b2L",
+// or "bestmove none" if the game is over. Scores are evaluations from the perspective of Player 1, proven wins are
+// written as "score win <plies>" if Player 1 wins and "score loss <plies>" if Player 2 wins, and time is given in
+// milliseconds. Errors are reported as "info string <message>".
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Config configures the engine that answers the commands
+type Config struct {
+	Engine ai.EngineSpec
+	Limits ai.Limits // Limits of go commands without depth and movetime, overridden by the depth and time options of the engine spec
+}
+
+// engine is the state of the engine side of the protocol
+type engine struct {
+	config Config
+	out    io.Writer
+	outMu  sync.Mutex
+
+	engine ai.Engine
+	limits ai.Limits
+	rules  game.Rules
+	board  *game.Board
+
+	searching chan struct{}      // Closed when the running search finished, nil if no search was started
+	stop      context.CancelFunc // Stops the running search
+}
+
+// Serve reads commands from in and writes the answers of the engine to out until quit is received,
+// in is exhausted or ctx is done
+func Serve(ctx context.Context, in io.Reader, out io.Writer, config Config) error {
+	e := &engine{config: config, out: out, rules: game.DefaultRules}
+	if err := e.newGame(game.DefaultRules); err != nil {
+		return err
+	}
+	defer func() { _ = ai.CloseEngine(e.engine) }()
+	defer e.stopSearch()
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+		close(lines)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				e.waitForSearch()
+				return <-readErr
+			}
+			if quit := e.handle(ctx, strings.Fields(line)); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// handle executes a command and reports whether the engine should quit
+func (e *engine) handle(ctx context.Context, fields []string) (quit bool) {
+	if len(fields) == 0 {
+		return false
+	}
+	command, args := fields[0], fields[1:]
+
+	// stop, isready and quit are handled during a search, all other commands wait for the running search
+	switch command {
+	case "stop":
+		e.stopSearch()
+		return false
+	case "isready":
+		e.waitForSearch()
+		e.println("readyok")
+		return false
+	case "quit":
+		return true
+	}
+	e.waitForSearch()
+
+	var err error
+	switch command {
+	case "gobblet":
+		e.println("id name Gobblet Gobblers " + e.config.Engine.String())
+		e.println("gobbletok")
+	case "setoption":
+		err = e.setOption(args)
+	case "newgame":
+		rules := game.DefaultRules
+		if len(args) > 0 {
+			rules, err = game.ParseRules(strings.Join(args, " "))
+		}
+		if err == nil {
+			err = e.newGame(rules)
+		}
+	case "position":
+		err = e.position(args)
+	case "go":
+		err = e.goSearch(ctx, args)
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		e.println("info string " + err.Error())
+	}
+	return false
+}
+
+// setOption handles "setoption name <key> value <value>"
+func (e *engine) setOption(args []string) error {
+	if len(args) < 4 || args[0] != "name" || args[2] != "value" {
+		return errors.New("setoption must be written as: setoption name <key> value <value>")
+	}
+	key, value := args[1], strings.Join(args[3:], " ")
+
+	spec := ai.EngineSpec{Name: e.config.Engine.Name, Options: make(map[string]string)}
+	for k, v := range e.config.Engine.Options {
+		spec.Options[k] = v
+	}
+	if key == "engine" {
+		parsed, err := ai.ParseEngineSpec(value)
+		if err != nil {
+			return err
+		}
+		spec = parsed
+	} else {
+		spec.Options[key] = value
+	}
+
+	engine, limits, err := ai.NewEngine(spec, e.rules, e.config.Limits)
+	if err != nil {
+		return err
+	}
+	_ = ai.CloseEngine(e.engine)
+	e.config.Engine, e.engine, e.limits = spec, engine, limits
+	return nil
+}
+
+// newGame creates a new engine, so no knowledge of previous games is kept, and sets up the start position
+func (e *engine) newGame(rules game.Rules) error {
+	engine, limits, err := ai.NewEngine(e.config.Engine, rules, e.config.Limits)
+	if err != nil {
+		return err
+	}
+	_ = ai.CloseEngine(e.engine)
+	e.engine, e.limits, e.rules = engine, limits, rules
+	e.board = game.NewBoardWithRules(rules)
+	return nil
+}
+
+// position handles "position startpos|<encoded> [moves ...]". The board is only changed if all moves are valid.
+func (e *engine) position(args []string) error {
+	if len(args) == 0 {
+		return errors.New("position needs startpos or an encoded position")
+	}
+	encoded, moves, _ := strings.Cut(strings.Join(args, " "), "moves")
+
+	board := game.NewBoardWithRules(e.rules)
+	if encoded = strings.TrimSpace(encoded); encoded != "startpos" {
+		var err error
+		if board, err = game.ParsePositionWithRules(encoded, e.rules); err != nil {
+			return err
+		}
+	}
+	for _, token := range strings.Fields(moves) {
+		move, err := ParseMove(token, board)
+		if err != nil {
+			return err
+		}
+		if err := board.MakeMove(move); err != nil {
+			return fmt.Errorf("move %s: %w", token, err)
+		}
+	}
+	e.board = board
+	return nil
+}
+
+// goSearch handles "go [depth <plies>] [movetime <ms>]" and starts the search in the background
+func (e *engine) goSearch(ctx context.Context, args []string) error {
+	limits := e.limits
+	for i := 0; i+1 < len(args); i += 2 {
+		value, err := strconv.Atoi(args[i+1])
+		if err != nil || value < 0 {
+			return fmt.Errorf("invalid value %q of %s", args[i+1], args[i])
+		}
+		switch args[i] {
+		case "depth":
+			limits.MaxDepth = value
+		case "movetime":
+			limits.MoveTime = time.Duration(value) * time.Millisecond
+		default:
+			return fmt.Errorf("unknown search limit %q", args[i])
+		}
+	}
+	if len(args)%2 != 0 {
+		return fmt.Errorf("search limit %q has no value", args[len(args)-1])
+	}
+
+	if e.board.Outcome().IsOver() {
+		e.println("bestmove none")
+		return nil
+	}
+
+	// Every completed iteration is reported while the engine searches, the result only if it was not reported yet,
+	// e.g. for engines that do not search in iterations
+	reported := false
+	limits.OnIteration = func(iteration ai.Iteration) {
+		e.printInfo(iteration.Depth, iteration.Evaluation, iteration.Nodes, iteration.Elapsed, iteration.PV)
+		reported = true
+	}
+
+	ctx, e.stop = context.WithCancel(ctx)
+	e.searching = make(chan struct{})
+	go func(engine ai.Engine, board *game.Board, searching chan struct{}) {
+		defer close(searching)
+		result, _ := engine.Search(ctx, board, limits)
+		if !reported {
+			e.printInfo(result.Depth, result.Evaluation, result.Nodes, result.Elapsed, result.PV)
+		}
+		e.println("bestmove " + FormatMove(result.BestMove))
+	}(e.engine, e.board, e.searching)
+	return nil
+}
+
+// printInfo writes an info line with the result of a search
+func (e *engine) printInfo(depth, evaluation, nodes int, elapsed time.Duration, pv []game.Move) {
+	e.println(fmt.Sprintf("info depth %d score %s nodes %d time %d pv %s", depth, formatScore(evaluation), nodes,
+		elapsed.Milliseconds(), formatMoves(pv)))
+}
+
+// formatScore writes proven wins of known distance as "win <plies>" or "loss <plies>", and all other evaluations as numbers
+func formatScore(evaluation int) string {
+	switch winIn := ai.WinIn(evaluation); {
+	case winIn > 0:
+		return "win " + strconv.Itoa(winIn)
+	case winIn < 0:
+		return "loss " + strconv.Itoa(-winIn)
+	default:
+		return strconv.Itoa(evaluation)
+	}
+}
+
+func (e *engine) stopSearch() {
+	if e.stop != nil {
+		e.stop()
+	}
+	e.waitForSearch()
+}
+
+func (e *engine) waitForSearch() {
+	if e.searching != nil {
+		<-e.searching
+		e.searching = nil
+		e.stop()
+	}
+}
+
+func (e *engine) println(line string) {
+	e.outMu.Lock()
+	defer e.outMu.Unlock()
+	_, _ = fmt.Fprintln(e.out, line)
+}
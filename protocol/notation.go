@@ -0,0 +1,33 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// FormatMove returns the move in the notation of the protocol, the notation of game.MoveString without the space,
+// or "none" for the zero move
+func FormatMove(move game.Move) string {
+	if move == (game.Move{}) {
+		return "none"
+	}
+	return strings.ReplaceAll(game.MoveString(move), " ", "")
+}
+
+// ParseMove parses a move in the notation of the protocol, e.g. b2S or a1c3, for the active player of the board
+func ParseMove(token string, board *game.Board) (game.Move, error) {
+	if len(token) != 3 && len(token) != 4 {
+		return game.Move{}, errors.New("invalid move " + token)
+	}
+	return game.ParseMove(token[:2]+" "+token[2:], board)
+}
+
+func formatMoves(moves []game.Move) string {
+	tokens := make([]string, len(moves))
+	for i, move := range moves {
+		tokens[i] = FormatMove(move)
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,206 @@
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+// Player 1 wins by placing a piece on c1
+const winInOne = "L,M,-/s,m,-/-,-,- 1 211/112"
+
+// testEngine runs Serve in the background and returns the ends of its input and output
+func testEngine(t *testing.T) (commands io.WriteCloser, answers io.Reader) {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	config := Config{Engine: ai.EngineSpec{Name: "minimax"}, Limits: ai.Limits{MaxDepth: 3}}
+	go func() {
+		_ = Serve(context.Background(), inReader, outWriter, config)
+		_ = outWriter.Close()
+	}()
+	t.Cleanup(func() { _ = inWriter.Close() })
+	return inWriter, outReader
+}
+
+// engineSession sends commands to an engine and reads its answers line by line
+type engineSession struct {
+	t        *testing.T
+	commands io.Writer
+	lines    chan string
+}
+
+func newEngineSession(t *testing.T) *engineSession {
+	commands, answers := testEngine(t)
+	s := &engineSession{t: t, commands: commands, lines: make(chan string, 100)}
+	go func() {
+		scanner := bufio.NewScanner(answers)
+		for scanner.Scan() {
+			s.lines <- scanner.Text()
+		}
+		close(s.lines)
+	}()
+	return s
+}
+
+func (s *engineSession) send(command string) {
+	_, err := io.WriteString(s.commands, command+"\n")
+	assert.NoError(s.t, err)
+}
+
+// readUntil returns the first line with the prefix, skipping all other lines
+func (s *engineSession) readUntil(prefix string) string {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.t.Fatalf("engine exited while waiting for %q", prefix)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			s.t.Fatalf("no answer %q", prefix)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := newEngineSession(t)
+
+	s.send("gobblet")
+	assert.Equal(t, "id name Gobblet Gobblers minimax", s.readUntil("id name"))
+	s.readUntil("gobbletok")
+
+	s.send("position " + winInOne)
+	s.send("go depth 1")
+	info := s.readUntil("info")
+	assert.Contains(t, info, "depth 1 score win 1")
+	assert.Contains(t, info, "pv c1")
+	assert.True(t, strings.HasPrefix(s.readUntil("bestmove"), "bestmove c1"))
+
+	s.send("position startpos moves b2L a1S b2a1")
+	s.send("go depth 2")
+	s.readUntil("bestmove")
+
+	s.send("position startpos moves b2L b2S")
+	assert.Equal(t, "info string move b2S: piece not larger than existing piece on position", s.readUntil("info string"))
+	s.send("fly")
+	assert.Equal(t, `info string unknown command "fly"`, s.readUntil("info string"))
+	s.send("go depth")
+	assert.Equal(t, `info string search limit "depth" has no value`, s.readUntil("info string"))
+
+	// A finished game has no best move
+	s.send("position " + winInOne + " moves c1S")
+	s.send("go")
+	assert.Equal(t, "bestmove none", s.readUntil("bestmove"))
+
+	// Every completed iteration is reported while the engine searches
+	s.send("position startpos")
+	s.send("go depth 3 movetime 60000")
+	for depth := 1; depth <= 3; depth++ {
+		assert.Regexp(t, fmt.Sprintf(`^info depth %d score -?\d+ nodes \d+ time \d+ pv( [a-c][1-3]\w+)+$`, depth), s.readUntil("info"))
+	}
+	s.readUntil("bestmove")
+
+	s.send("newgame gobblet")
+	s.send("go depth 1")
+	assert.Regexp(t, "^bestmove [a-d][1-4]X$", s.readUntil("bestmove"), "a new game starts from the start position of the rules")
+
+	s.send("setoption name engine value random:seed=1")
+	s.send("go")
+	assert.Regexp(t, "^bestmove [a-d][1-4]X$", s.readUntil("bestmove"))
+	s.send("setoption name engine value chess")
+	assert.Contains(t, s.readUntil("info string"), "unknown engine")
+
+	s.send("quit")
+	_, open := <-s.lines
+	assert.False(t, open, "the engine must exit")
+}
+
+func TestServeStop(t *testing.T) {
+	s := newEngineSession(t)
+
+	s.send("go depth 30 movetime 60000")
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	s.send("stop")
+	s.readUntil("bestmove ")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	s.send("isready")
+	s.readUntil("readyok")
+}
+
+func TestClient(t *testing.T) {
+	commands, answers := testEngine(t)
+	client, err := NewClient(answers, commands)
+	assert.NoError(t, err)
+	assert.Equal(t, "Gobblet Gobblers minimax", client.Name)
+
+	board, err := game.ParsePosition(winInOne)
+	assert.NoError(t, err)
+	var iterations []ai.Iteration
+	result, err := client.Search(context.Background(), board, ai.Limits{MaxDepth: 1, OnIteration: func(iteration ai.Iteration) {
+		iterations = append(iterations, iteration)
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, result.Iterations, iterations, "info lines of the engine must be reported")
+	assert.Equal(t, ai.WinScore(game.Player1, 1), result.Evaluation)
+	assert.Equal(t, 1, result.WinIn)
+	assert.Equal(t, 1, result.Depth)
+	assert.Equal(t, []game.Move{result.BestMove}, result.PV)
+	board.MustMakeMove(result.BestMove)
+	assert.Equal(t, game.Player1, board.CheckWin())
+
+	// The rules of the board are sent to the engine
+	board = game.NewBoardWithRules(game.GobbletRules)
+	result, err = client.Search(context.Background(), board, ai.Limits{MaxDepth: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, game.ExtraLarge, result.BestMove.Piece.Size)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	board = game.NewBoard()
+	result, err = client.Search(ctx, board, ai.Limits{MaxDepth: 30, MoveTime: time.Minute})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, board.MakeMove(result.BestMove), "a stopped search must return a valid move")
+
+	assert.NoError(t, client.Close())
+}
+
+func TestExternalEngine(t *testing.T) {
+	_, _, err := ai.NewEngine(ai.EngineSpec{Name: "external", Options: map[string]string{}}, game.DefaultRules, ai.Limits{})
+	assert.ErrorContains(t, err, "option command is required")
+	_, _, err = ai.NewEngine(ai.EngineSpec{Name: "external", Options: map[string]string{"command": "/nonexistent/engine"}}, game.DefaultRules, ai.Limits{})
+	assert.Error(t, err)
+}
+
+func TestParseInfo(t *testing.T) {
+	board := game.NewBoard()
+	for _, test := range []struct {
+		line       string
+		evaluation int
+		winIn      int
+	}{
+		{"depth 3 score 42 nodes 100 pv b2L", 42, 0},
+		{"depth 3 score win 5 nodes 100 pv b2L", ai.WinScore(game.Player1, 5), 5},
+		{"depth 3 score loss 4 nodes 100 pv b2L", ai.WinScore(game.Player2, 4), -4},
+	} {
+		result := ai.SearchResult{}
+		parseInfo(strings.Fields(test.line), board, &result)
+		assert.Equal(t, test.evaluation, result.Evaluation, test.line)
+		assert.Equal(t, test.winIn, result.WinIn, test.line)
+		assert.Equal(t, 3, result.Depth, test.line)
+		assert.Equal(t, 100, result.Nodes, test.line)
+		assert.Len(t, result.PV, 1, test.line)
+	}
+}
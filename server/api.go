@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+type createGameRequest struct {
+	Rules    string `json:"rules"`    // Variant or rule options as read by game.ParseRules, standard if empty
+	Position string `json:"position"` // Start position in the format of game.Board.Encode, the start position of the rules if empty
+	MaxPlies *int   `json:"maxPlies"` // Draw the game if it is not decided after this many plies, 0 for no limit
+	Engine   string `json:"engine"`   // Player whose moves the engine makes without being asked: player1 or player2
+}
+
+type moveRequest struct {
+	Move string `json:"move"` // Move in the notation of game.ParseMove, e.g. "b2 S" or "a1 c3"
+	From string `json:"from"`
+	To   string `json:"to"`
+	Size string `json:"size"` // Size of a new piece: S, M, L or X
+}
+
+type engineMoveRequest struct {
+	Depth int    `json:"depth"` // Maximum search depth, the default of the engine if zero
+	Time  string `json:"time"`  // Time to think, e.g. "2s", the default of the engine if empty
+}
+
+type engineMoveResponse struct {
+	Move       string    `json:"move"`
+	Evaluation int       `json:"evaluation"` // From the perspective of Player 1
+	Depth      int       `json:"depth"`
+	Nodes      int       `json:"nodes"`
+	PV         []string  `json:"pv"`
+	Elapsed    int64     `json:"elapsedMs"`
+	Game       gameState `json:"game"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type gameState struct {
+	ID              string            `json:"id"`
+	Rules           string            `json:"rules"`
+	Position        string            `json:"position"` // Encoded position, see game.Board.Encode
+	Board           [][][]pieceState  `json:"board"`    // Stacks of all positions by row and column, from bottom to top
+	RemainingPieces map[string][]int  `json:"remainingPieces"`
+	ActivePlayer    int               `json:"activePlayer"`
+	Winner          string            `json:"winner"` // none, player1, player2 or draw
+	Moves           []string          `json:"moves"`
+	LastMove        string            `json:"lastMove,omitempty"`
+	Players         map[string]string `json:"players"` // engine, connected, disconnected or open for both players
+	Spectators      int               `json:"spectators"`
+}
+
+type pieceState struct {
+	Player int    `json:"player"`
+	Size   string `json:"size"`
+}
+
+var playerNames = map[game.Player]string{
+	game.None:    "none",
+	game.Player1: "player1",
+	game.Player2: "player2",
+}
+
+// outcomeNames are the winners of the game state, draws have no winner
+var outcomeNames = map[game.Outcome]string{
+	game.Undecided:   "none",
+	game.Player1Wins: "player1",
+	game.Player2Wins: "player2",
+	game.Drawn:       "draw",
+}
+
+// parsePlayer returns the player with the name of playerNames
+func parsePlayer(name string) (game.Player, bool) {
+	for player, playerName := range playerNames {
+		if playerName == name && (player == game.Player1 || player == game.Player2) {
+			return player, true
+		}
+	}
+	return game.None, false
+}
+
+func parseEnginePlayer(name string) (game.Player, error) {
+	if name == "" {
+		return game.None, nil
+	}
+	if player, ok := parsePlayer(name); ok {
+		return player, nil
+	}
+	return game.None, fmt.Errorf("invalid engine player %q: must be player1 or player2", name)
+}
+
+func newGameState(g *session) gameState {
+	board := g.board
+	grid := make([][][]pieceState, board.Rules.BoardSize)
+	for row := range grid {
+		grid[row] = make([][]pieceState, board.Rules.BoardSize)
+		for col := range grid[row] {
+			stack := []pieceState{}
+			for _, piece := range board.Get(row, col).Pieces {
+				stack = append(stack, pieceState{Player: int(piece.Owner), Size: piece.Size.String()})
+			}
+			grid[row][col] = stack
+		}
+	}
+
+	state := gameState{
+		ID:       g.id,
+		Rules:    board.Rules.String(),
+		Position: board.Encode(),
+		Board:    grid,
+		RemainingPieces: map[string][]int{
+			playerNames[game.Player1]: slices.Clone(board.RemainingPieces[game.Player1]),
+			playerNames[game.Player2]: slices.Clone(board.RemainingPieces[game.Player2]),
+		},
+		ActivePlayer: int(board.ActivePlayer),
+		Winner:       outcomeNames[board.Outcome()],
+		Moves:        append([]string{}, g.moves...),
+		Players:      make(map[string]string),
+		Spectators:   len(g.spectators),
+	}
+	for _, player := range []game.Player{game.Player1, game.Player2} {
+		state.Players[playerNames[player]] = g.playerStatus(player)
+	}
+	if len(g.moves) > 0 {
+		state.LastMove = g.moves[len(g.moves)-1]
+	}
+	return state
+}
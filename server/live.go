@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	spectatorRole = "spectator"
+
+	// sendBuffer is the number of messages queued for a connection. Connections that fall further behind are closed.
+	sendBuffer   = 16
+	writeTimeout = 10 * time.Second
+	// pongTimeout closes connections that did not answer a ping in time, pings are sent every pingPeriod
+	pongTimeout = 60 * time.Second
+	pingPeriod  = pongTimeout * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{}
+
+// liveMessage is sent over the WebSocket connections of a game in both directions.
+// Clients send moves, the server sends the role of a new connection, the state after every change and errors.
+type liveMessage struct {
+	Type  string     `json:"type"`            // move from clients, joined, state or error from the server
+	Move  string     `json:"move,omitempty"`  // Move in the notation of game.ParseMove
+	Role  string     `json:"role,omitempty"`  // player1, player2 or spectator
+	Token string     `json:"token,omitempty"` // Token of a player, required to connect to the seat again
+	Game  *gameState `json:"game,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// live holds the WebSocket connections of a game
+type live struct {
+	seats      map[game.Player]*seat
+	spectators map[*connection]bool
+	removed    bool // Whether the game was removed from the store, new connections are refused
+}
+
+// seat is taken by the first connection of a player, and can only be taken again with its token
+type seat struct {
+	token string
+	conn  *connection // nil while the player is not connected
+}
+
+func newLive() live {
+	return live{
+		seats:      map[game.Player]*seat{game.Player1: {}, game.Player2: {}},
+		spectators: make(map[*connection]bool),
+	}
+}
+
+// playerStatus returns engine, connected, disconnected (the seat was taken, but the connection is gone) or open
+func (g *session) playerStatus(player game.Player) string {
+	seat := g.seats[player]
+	switch {
+	case player == g.enginePlayer:
+		return "engine"
+	case seat == nil || seat.token == "":
+		return "open"
+	case seat.conn != nil:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+// broadcast sends the message to all connections of the game. The lock of the game must be held.
+func (g *session) broadcast(message liveMessage) {
+	for _, seat := range g.seats {
+		if seat.conn != nil {
+			seat.conn.send(message)
+		}
+	}
+	for c := range g.spectators {
+		c.send(message)
+	}
+}
+
+// leave removes the connection from the game, unless it was replaced already. The lock of the game must be held.
+func (g *session) leave(c *connection) {
+	c.close("")
+	left := g.spectators[c]
+	delete(g.spectators, c)
+	for _, seat := range g.seats {
+		if seat.conn == c {
+			seat.conn, left = nil, true
+		}
+	}
+	if left {
+		g.broadcast(stateMessage(g))
+	}
+}
+
+// disconnectAll closes all connections of a removed game and cancels the search of the engine.
+// The lock of the game must be held.
+func (g *session) disconnectAll() {
+	g.removed = true
+	if g.cancelSearch != nil {
+		g.cancelSearch()
+	}
+	for _, seat := range g.seats {
+		if seat.conn != nil {
+			seat.conn.close("game was removed")
+			seat.conn = nil
+		}
+	}
+	for c := range g.spectators {
+		c.close("game was removed")
+		delete(g.spectators, c)
+	}
+}
+
+func stateMessage(g *session) liveMessage {
+	state := newGameState(g)
+	return liveMessage{Type: "state", Game: &state}
+}
+
+func errorMessage(err error) liveMessage {
+	return liveMessage{Type: "error", Error: err.Error()}
+}
+
+// connect upgrades the request to a WebSocket connection to the game. The query parameter role selects player1,
+// player2 or spectator (the default). The first connection of a player takes the seat and receives a token, later
+// connections of the player, e.g. after losing the connection, must present it with the query parameter token.
+// A new connection of a player replaces the previous one. Spectators only receive the state of the game.
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	g, found := s.games.get(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("game not found"))
+		return
+	}
+	c, player := s.join(w, r, g)
+	if c == nil {
+		return
+	}
+
+	defer func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.leave(c)
+	}()
+	c.ws.SetReadLimit(maxBodySize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	// Connected clients answer pings, which keeps the game from being removed as idle while nobody moves
+	c.ws.SetPongHandler(func(string) error {
+		g.touch()
+		return c.ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		g.touch()
+		var message liveMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			g.mu.Lock()
+			c.send(errorMessage(err))
+			g.mu.Unlock()
+			continue
+		}
+
+		g.mu.Lock()
+		if err := s.handleMessage(g, player, message); err != nil {
+			c.send(errorMessage(err))
+		}
+		g.mu.Unlock()
+	}
+}
+
+// join checks the role of the request, upgrades the connection and adds it to the game.
+// It returns nil if the request was answered with an error.
+func (s *Server) join(w http.ResponseWriter, r *http.Request, g *session) (*connection, game.Player) {
+	role := r.URL.Query().Get("role")
+	if role == "" {
+		role = spectatorRole
+	}
+	player := game.None
+	if role != spectatorRole {
+		var ok bool
+		if player, ok = parsePlayer(role); !ok {
+			writeError(w, http.StatusBadRequest, errors.New("role must be player1, player2 or spectator"))
+			return nil, game.None
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.removed {
+		writeError(w, http.StatusNotFound, errors.New("game not found"))
+		return nil, game.None
+	}
+	seat := g.seats[player]
+	if seat != nil && player == g.enginePlayer {
+		writeError(w, http.StatusConflict, errors.New(role+" is played by the engine"))
+		return nil, game.None
+	}
+	if seat != nil && seat.token != "" && r.URL.Query().Get("token") != seat.token {
+		writeError(w, http.StatusForbidden, errors.New(role+" is taken, connecting again requires its token"))
+		return nil, game.None
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader answered the request already
+		return nil, game.None
+	}
+	c := newConnection(ws)
+	joined := liveMessage{Type: "joined", Role: role}
+	if seat != nil {
+		if seat.conn != nil {
+			seat.conn.close("replaced by a new connection")
+		}
+		if seat.token == "" {
+			seat.token = newID()
+		}
+		seat.conn = c
+		joined.Token = seat.token
+	} else {
+		g.spectators[c] = true
+	}
+	c.send(joined)
+	g.broadcast(stateMessage(g))
+	return c, player
+}
+
+// handleMessage makes the move of a message sent by the player. The lock of the game must be held.
+func (s *Server) handleMessage(g *session, player game.Player, message liveMessage) error {
+	if message.Type != "move" {
+		return errors.New("unknown message type " + message.Type)
+	}
+	if player == game.None {
+		return errors.New("spectators can not make moves")
+	}
+	if player != g.board.ActivePlayer && !g.board.Outcome().IsOver() {
+		return errors.New("it is not your turn")
+	}
+	if err := s.play(g, message.Move); err != nil {
+		return err
+	}
+	return nil
+}
+
+// scheduleEngineMove lets the engine search a move in the background if the engine player is to move.
+// The engine searches a copy of the board without holding the lock, so requests and messages are handled while it
+// thinks. The move is only made if the position did not change in the meantime, and removing the game cancels the
+// search. The lock of the game must be held.
+func (s *Server) scheduleEngineMove(g *session) {
+	if g.thinking || g.removed || g.board.ActivePlayer != g.enginePlayer || g.board.Outcome().IsOver() {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.thinking, g.cancelSearch = true, cancel
+	board, plies, hash := g.board.Clone(), g.board.Plies(), g.board.Hash
+	limits, _ := s.limits(g, engineMoveRequest{})
+
+	go func() {
+		defer cancel()
+		result, err := g.engine.Search(ctx, board, limits)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.thinking, g.cancelSearch = false, nil
+		switch {
+		case g.removed:
+			_ = ai.CloseEngine(g.engine)
+		case err != nil:
+			g.broadcast(errorMessage(err))
+		case g.board.Plies() != plies || g.board.Hash != hash:
+			// The move belongs to another position, search again if the engine is still to move
+			s.scheduleEngineMove(g)
+		default:
+			move := g.board.TransformMove(result.BestMove, game.Identity)
+			g.board.MustMakeMove(move)
+			s.moved(g, move)
+		}
+	}()
+}
+
+// connection writes messages to a WebSocket from a single goroutine, as required by gorilla/websocket
+type connection struct {
+	ws       *websocket.Conn
+	messages chan liveMessage
+	closed   bool   // Guarded by the lock of the game
+	reason   string // Sent to the client when the connection is closed by the server
+}
+
+func newConnection(ws *websocket.Conn) *connection {
+	c := &connection{ws: ws, messages: make(chan liveMessage, sendBuffer)}
+	go c.write()
+	return c
+}
+
+// send queues the message, and closes connections that do not keep up. The lock of the game must be held.
+func (c *connection) send(message liveMessage) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.messages <- message:
+	default:
+		c.close("too many unsent messages")
+	}
+}
+
+// close closes the connection after the queued messages were sent. The lock of the game must be held.
+func (c *connection) close(reason string) {
+	if !c.closed {
+		c.closed, c.reason = true, reason
+		close(c.messages)
+	}
+}
+
+func (c *connection) write() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.ws.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.messages:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				// reason was set before the channel was closed
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, c.reason)
+				_ = c.ws.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+			if err := c.ws.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// dial connects to the game with the query parameters
+func dial(t *testing.T, server *httptest.Server, id, query string) (*websocket.Conn, int) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/games/" + id + "/ws?" + query
+	conn, response, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		assert.NotNil(t, response, err)
+		return nil, response.StatusCode
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, response.StatusCode
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) liveMessage {
+	var message liveMessage
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	assert.NoError(t, conn.ReadJSON(&message))
+	return message
+}
+
+// readState reads messages until the state of the game fulfills the condition
+func readState(t *testing.T, conn *websocket.Conn, condition func(state *gameState) bool) *gameState {
+	for {
+		message := readMessage(t, conn)
+		if t.Failed() {
+			return nil
+		}
+		if message.Type == "state" && condition(message.Game) {
+			return message.Game
+		}
+	}
+}
+
+// readError reads messages until an error arrives
+func readError(t *testing.T, conn *websocket.Conn) string {
+	for {
+		message := readMessage(t, conn)
+		if t.Failed() || message.Type == "error" {
+			return message.Error
+		}
+	}
+}
+
+func plies(n int) func(state *gameState) bool {
+	return func(state *gameState) bool {
+		return len(state.Moves) == n
+	}
+}
+
+func TestLiveGame(t *testing.T) {
+	server := newTestServer(t)
+	id := createGame(t, server, nil).ID
+
+	player1, status := dial(t, server, id, "role=player1")
+	assert.Equal(t, http.StatusSwitchingProtocols, status)
+	joined := readMessage(t, player1)
+	assert.Equal(t, "joined", joined.Type)
+	assert.Equal(t, "player1", joined.Role)
+	assert.NotEmpty(t, joined.Token)
+	player2, _ := dial(t, server, id, "role=player2")
+	token2 := readMessage(t, player2).Token
+	spectator, _ := dial(t, server, id, "")
+	assert.Equal(t, liveMessage{Type: "joined", Role: "spectator"}, readMessage(t, spectator))
+
+	state := readState(t, player1, func(state *gameState) bool { return state.Spectators == 1 })
+	assert.Equal(t, map[string]string{"player1": "connected", "player2": "connected"}, state.Players)
+
+	assert.NoError(t, player2.WriteJSON(liveMessage{Type: "move", Move: "b2 L"}))
+	assert.Equal(t, "it is not your turn", readError(t, player2))
+	assert.NoError(t, spectator.WriteJSON(liveMessage{Type: "move", Move: "b2 L"}))
+	assert.Equal(t, "spectators can not make moves", readError(t, spectator))
+
+	assert.NoError(t, player1.WriteJSON(liveMessage{Type: "move", Move: "b2 L"}))
+	for _, conn := range []*websocket.Conn{player1, player2, spectator} {
+		state = readState(t, conn, plies(1))
+		assert.Equal(t, "b2 L", state.LastMove)
+		assert.Equal(t, 2, state.ActivePlayer)
+	}
+
+	assert.NoError(t, player2.WriteJSON(liveMessage{Type: "move", Move: "b2 S"}))
+	assert.NotEmpty(t, readError(t, player2), "moves are validated by the board")
+	assert.NoError(t, player2.WriteMessage(websocket.TextMessage, []byte("b2 S")))
+	assert.NotEmpty(t, readError(t, player2))
+
+	// Moves of the REST API for a taken seat require its token, and are sent to all connections
+	movesURL := server.URL + "/games/" + id + "/moves"
+	assert.Equal(t, http.StatusForbidden, request(t, http.MethodPost, movesURL, "a1 S", nil))
+	assert.Equal(t, http.StatusForbidden, request(t, http.MethodPost, server.URL+"/games/"+id+"/engine-move", nil, nil))
+	var restState gameState
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, movesURL+"?token="+token2, "a1 S", &restState))
+	assert.Equal(t, "a1 S", readState(t, spectator, plies(2)).LastMove)
+}
+
+func TestReconnect(t *testing.T) {
+	server := newTestServer(t)
+	id := createGame(t, server, nil).ID
+
+	player1, _ := dial(t, server, id, "role=player1")
+	token := readMessage(t, player1).Token
+
+	_, status := dial(t, server, id, "role=player1")
+	assert.Equal(t, http.StatusForbidden, status, "taken seats require the token")
+	_, status = dial(t, server, id, "role=player3")
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status = dial(t, server, "unknown", "role=player1")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	reconnected, _ := dial(t, server, id, "role=player1&token="+token)
+	assert.Equal(t, token, readMessage(t, reconnected).Token)
+	// The previous connection is closed
+	for {
+		_ = player1.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, _, err := player1.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+			break
+		}
+	}
+
+	assert.NoError(t, reconnected.WriteJSON(liveMessage{Type: "move", Move: "a1 L"}))
+	readState(t, reconnected, plies(1))
+	assert.NoError(t, reconnected.Close())
+
+	var state gameState
+	assert.Eventually(t, func() bool {
+		request(t, http.MethodGet, server.URL+"/games/"+id, nil, &state)
+		return state.Players["player1"] == "disconnected"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "open", state.Players["player2"])
+}
+
+func TestLiveEngine(t *testing.T) {
+	server := newTestServer(t)
+	id := createGame(t, server, createGameRequest{Engine: "player2"}).ID
+
+	_, status := dial(t, server, id, "role=player2")
+	assert.Equal(t, http.StatusConflict, status, "the seat of the engine can not be taken")
+
+	player1, _ := dial(t, server, id, "role=player1")
+	assert.Equal(t, "engine", readState(t, player1, plies(0)).Players["player2"])
+	assert.NoError(t, player1.WriteJSON(liveMessage{Type: "move", Move: "b2 L"}))
+	state := readState(t, player1, plies(2))
+	assert.Equal(t, 1, state.ActivePlayer, "the engine replies without being asked")
+
+	// The engine starts if it plays Player 1
+	id = createGame(t, server, createGameRequest{Engine: "player1"}).ID
+	spectator, _ := dial(t, server, id, "")
+	assert.Equal(t, 2, readState(t, spectator, plies(1)).ActivePlayer)
+
+	var response errorResponse
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/games", createGameRequest{Engine: "both"}, &response))
+}
+
+func TestDeleteLiveGame(t *testing.T) {
+	server := newTestServer(t)
+	id := createGame(t, server, nil).ID
+	spectator, _ := dial(t, server, id, "")
+
+	assert.Equal(t, http.StatusNoContent, request(t, http.MethodDelete, server.URL+"/games/"+id, nil, nil))
+	for {
+		_ = spectator.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, _, err := spectator.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+			break
+		}
+	}
+}
+
+func TestEngineThinksWithoutLock(t *testing.T) {
+	s, err := New(Config{Engine: ai.EngineSpec{Name: "minimax"}, Limits: ai.Limits{MaxDepth: 20, MoveTime: time.Minute}})
+	assert.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	id := createGame(t, server, createGameRequest{Engine: "player1"}).ID
+
+	start := time.Now()
+	var state gameState
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/games/"+id, nil, &state))
+	assert.Empty(t, state.Moves, "the engine must still be thinking")
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/games/"+id+"/engine-move", nil, nil),
+		"the engine can only search one position at a time")
+	g, _ := s.games.get(id)
+	assert.Equal(t, http.StatusNoContent, request(t, http.MethodDelete, server.URL+"/games/"+id, nil, nil))
+	assert.Less(t, time.Since(start), 10*time.Second, "requests must not wait for the search")
+
+	assert.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return !g.thinking
+	}, 10*time.Second, 10*time.Millisecond, "removing the game must cancel the search")
+	assert.Empty(t, g.moves, "the move of a cancelled search must not be made")
+}
@@ -0,0 +1,419 @@
+// Package server exposes games against the engines as an HTTP/JSON API, and as live sessions over WebSockets
+// in which remote players, the engine and spectators take part in the same game
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// DefaultMaxPlies draws games that are not decided after this many plies, unless the game is created with another limit
+const DefaultMaxPlies = 200
+
+const (
+	// DefaultMaxGames is the default number of games a server holds at the same time
+	DefaultMaxGames = 100
+	// DefaultIdleTimeout is the default time after which unused games are removed
+	DefaultIdleTimeout = time.Hour
+	// DefaultMaxMoveTime is the default upper bound of the time of an engine move
+	DefaultMaxMoveTime = 10 * time.Second
+)
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 16
+
+// Config configures the games and engines of a server
+type Config struct {
+	Engine ai.EngineSpec // Engine that plays the engine moves of all games
+	Limits ai.Limits     // Default limits of engine moves, overridden by the depth and time options of the engine spec
+	// MaxLimits are upper bounds of the limits of engine moves. The depth is not bounded if zero, the time is
+	// DefaultMaxMoveTime if zero, so no engine move searches forever.
+	MaxLimits ai.Limits
+	MaxPlies  int // Default move limit of new games, DefaultMaxPlies if zero
+	// MaxGames is the number of games held at the same time, DefaultMaxGames if zero.
+	// Creating more games fails with 503 Service Unavailable.
+	MaxGames int
+	// IdleTimeout removes games without requests, live messages and connections for this long when a game is created,
+	// DefaultIdleTimeout if zero
+	IdleTimeout time.Duration
+}
+
+// Server handles the requests of the API:
+//
+//	POST   /games                  create a game
+//	GET    /games/{id}             get the state of a game
+//	DELETE /games/{id}             remove a game
+//	GET    /games/{id}/moves       list the legal moves
+//	POST   /games/{id}/moves       make a move
+//	POST   /games/{id}/engine-move let the engine make a move
+//	GET    /games/{id}/ws          connect as a player or spectator with a WebSocket, see connect
+type Server struct {
+	config Config
+	games  *store
+	mux    *http.ServeMux
+}
+
+// New creates a server. It returns an error if the engine spec of the config is invalid.
+func New(config Config) (*Server, error) {
+	if err := ai.ValidateEngineSpec(config.Engine); err != nil {
+		return nil, err
+	}
+	if config.MaxPlies == 0 {
+		config.MaxPlies = DefaultMaxPlies
+	}
+	if config.MaxGames <= 0 {
+		config.MaxGames = DefaultMaxGames
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultIdleTimeout
+	}
+	if config.MaxLimits.MoveTime <= 0 {
+		config.MaxLimits.MoveTime = DefaultMaxMoveTime
+	}
+
+	s := &Server{config: config, games: newStore(config.MaxGames, config.IdleTimeout), mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /games", s.createGame)
+	s.mux.HandleFunc("GET /games/{id}", s.withGame(s.getGame))
+	s.mux.HandleFunc("DELETE /games/{id}", s.deleteGame)
+	s.mux.HandleFunc("GET /games/{id}/moves", s.withGame(s.legalMoves))
+	s.mux.HandleFunc("POST /games/{id}/moves", s.withGame(s.makeMove))
+	s.mux.HandleFunc("POST /games/{id}/engine-move", s.withGame(s.engineMove))
+	s.mux.HandleFunc("GET /games/{id}/ws", s.connect)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// withGame looks up the game of the request and calls the handler while holding the lock of the game
+func (s *Server) withGame(handler func(w http.ResponseWriter, r *http.Request, g *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, found := s.games.get(r.PathValue("id"))
+		if !found {
+			writeError(w, http.StatusNotFound, errors.New("game not found"))
+			return
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		handler(w, r, g)
+	}
+}
+
+func (s *Server) createGame(w http.ResponseWriter, r *http.Request) {
+	var request createGameRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rules := game.DefaultRules
+	if request.Rules != "" {
+		var err error
+		if rules, err = game.ParseRules(request.Rules); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	board := game.NewBoardWithRules(rules)
+	if request.Position != "" {
+		var err error
+		if board, err = game.ParsePositionWithRules(request.Position, rules); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	board.DrawRules.MaxPlies = s.config.MaxPlies
+	if request.MaxPlies != nil {
+		board.DrawRules.MaxPlies = *request.MaxPlies
+	}
+
+	enginePlayer, err := parseEnginePlayer(request.Engine)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	engine, limits, err := ai.NewEngine(s.config.Engine, rules, s.config.Limits)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	g := &session{board: board, engine: engine, limits: limits, enginePlayer: enginePlayer, live: newLive()}
+	idle, err := s.games.add(g)
+	for _, removed := range idle {
+		s.removeGame(removed)
+	}
+	if err != nil {
+		_ = ai.CloseEngine(engine)
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s.scheduleEngineMove(g)
+	writeJSON(w, http.StatusCreated, newGameState(g))
+}
+
+func (s *Server) getGame(w http.ResponseWriter, _ *http.Request, g *session) {
+	writeJSON(w, http.StatusOK, newGameState(g))
+}
+
+func (s *Server) deleteGame(w http.ResponseWriter, r *http.Request) {
+	g, found := s.games.get(r.PathValue("id"))
+	if !found || !s.games.remove(g.id) {
+		writeError(w, http.StatusNotFound, errors.New("game not found"))
+		return
+	}
+	s.removeGame(g)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeGame closes the connections and the engine of a game that was removed from the store.
+// An engine that is still searching is closed when its search was cancelled, see scheduleEngineMove.
+func (s *Server) removeGame(g *session) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.disconnectAll()
+	if !g.thinking {
+		_ = ai.CloseEngine(g.engine)
+	}
+}
+
+func (s *Server) legalMoves(w http.ResponseWriter, _ *http.Request, g *session) {
+	moves := []string{}
+	if !g.board.Outcome().IsOver() {
+		for _, move := range g.board.GetPossibleMoves() {
+			moves = append(moves, game.MoveString(move))
+		}
+	}
+	writeJSON(w, http.StatusOK, moves)
+}
+
+func (s *Server) makeMove(w http.ResponseWriter, r *http.Request, g *session) {
+	notation, err := readMove(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := authorize(g, r); err != nil {
+		writeError(w, err.status, err)
+		return
+	}
+	if err := s.play(g, notation); err != nil {
+		writeError(w, err.status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newGameState(g))
+}
+
+func (s *Server) engineMove(w http.ResponseWriter, r *http.Request, g *session) {
+	var request engineMoveRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := s.limits(g, request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := authorize(g, r); err != nil {
+		writeError(w, err.status, err)
+		return
+	}
+
+	result, moveErr := s.playEngineMove(r.Context(), g, limits)
+	if moveErr != nil {
+		writeError(w, moveErr.status, moveErr)
+		return
+	}
+	pv := make([]string, len(result.PV))
+	for i, move := range result.PV {
+		pv[i] = game.MoveString(move)
+	}
+	writeJSON(w, http.StatusOK, engineMoveResponse{
+		Move:       game.MoveString(result.BestMove),
+		Evaluation: result.Evaluation,
+		Depth:      result.Depth,
+		Nodes:      result.Nodes,
+		PV:         pv,
+		Elapsed:    result.Elapsed.Milliseconds(),
+		Game:       newGameState(g),
+	})
+}
+
+// requestError is an error caused by a request, together with the HTTP status reported to the client
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// authorize checks that a request may move for the active player. Once a player took a seat in a live game,
+// requests must present the token of the seat with the query parameter token to move for the player.
+// The lock of the game must be held.
+func authorize(g *session, r *http.Request) *requestError {
+	seat := g.seats[g.board.ActivePlayer]
+	if seat != nil && seat.token != "" && r.URL.Query().Get("token") != seat.token {
+		return &requestError{http.StatusForbidden, fmt.Errorf("%s is taken, moves require its token", playerNames[g.board.ActivePlayer])}
+	}
+	return nil
+}
+
+// play makes the move given in the notation of game.ParseMove for the active player.
+// The lock of the game must be held.
+func (s *Server) play(g *session, notation string) *requestError {
+	if g.board.Outcome().IsOver() {
+		return &requestError{http.StatusConflict, errors.New("game is over")}
+	}
+	if g.board.ActivePlayer == g.enginePlayer {
+		return &requestError{http.StatusConflict, errors.New("waiting for the engine to move")}
+	}
+
+	move, err := game.ParseMove(notation, g.board)
+	if err != nil {
+		return &requestError{http.StatusBadRequest, fmt.Errorf("%w: %q", err, notation)}
+	}
+	if err := g.board.MakeMove(move); err != nil {
+		return &requestError{http.StatusUnprocessableEntity, err}
+	}
+	s.moved(g, move)
+	return nil
+}
+
+// playEngineMove lets the engine search and make a move for the active player. Like scheduleEngineMove, the engine
+// searches a copy of the board and the move is only made if the position did not change in the meantime.
+// The lock of the game must be held, it is released during the search.
+func (s *Server) playEngineMove(ctx context.Context, g *session, limits ai.Limits) (ai.SearchResult, *requestError) {
+	if g.board.Outcome().IsOver() {
+		return ai.SearchResult{}, &requestError{http.StatusConflict, errors.New("game is over")}
+	}
+	if g.thinking {
+		// The engine can only search one position at a time
+		return ai.SearchResult{}, &requestError{http.StatusConflict, errors.New("waiting for the engine to move")}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g.thinking, g.cancelSearch = true, cancel
+	board, plies, hash := g.board.Clone(), g.board.Plies(), g.board.Hash
+
+	g.mu.Unlock()
+	result, err := g.engine.Search(ctx, board, limits)
+	g.mu.Lock()
+
+	g.thinking, g.cancelSearch = false, nil
+	switch {
+	case g.removed:
+		_ = ai.CloseEngine(g.engine)
+		return result, &requestError{http.StatusNotFound, errors.New("game not found")}
+	case g.board.Plies() != plies || g.board.Hash != hash:
+		// Moves made during the search cancel it and may have left the engine player to move
+		s.scheduleEngineMove(g)
+		return result, &requestError{http.StatusConflict, errors.New("the position changed during the search")}
+	case err != nil:
+		// The client went away, nobody is waiting for the move
+		return result, &requestError{http.StatusServiceUnavailable, err}
+	}
+	move := g.board.TransformMove(result.BestMove, game.Identity)
+	g.board.MustMakeMove(move)
+	s.moved(g, move)
+	return result, nil
+}
+
+// moved records a move that was made on the board of the game, sends the new state to all connections
+// and lets the engine reply if it plays the next move. A running search of the previous position is cancelled.
+func (s *Server) moved(g *session, move game.Move) {
+	if g.cancelSearch != nil {
+		g.cancelSearch()
+	}
+	g.moves = append(g.moves, game.MoveString(move))
+	g.broadcast(stateMessage(g))
+	s.scheduleEngineMove(g)
+}
+
+// limits returns the limits of an engine move: the requested limits, or the limits of the engine if not requested,
+// bounded by the maximum limits of the server
+func (s *Server) limits(g *session, request engineMoveRequest) (ai.Limits, error) {
+	limits := g.limits
+	if request.Depth > 0 {
+		limits.MaxDepth = request.Depth
+	}
+	if request.Time != "" {
+		moveTime, err := time.ParseDuration(request.Time)
+		if err != nil {
+			return limits, fmt.Errorf("invalid time: %w", err)
+		}
+		limits.MoveTime = moveTime
+	}
+
+	if maxDepth := s.config.MaxLimits.MaxDepth; maxDepth > 0 {
+		limits.MaxDepth = min(limits.MaxDepth, maxDepth)
+	}
+	if maxTime := s.config.MaxLimits.MoveTime; maxTime > 0 && (limits.MoveTime <= 0 || limits.MoveTime > maxTime) {
+		limits.MoveTime = maxTime
+	}
+	return limits, nil
+}
+
+// readJSON decodes the JSON body of the request into v. An empty body leaves v unchanged.
+func readJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// readMove returns the move of the request in the notation of game.ParseMove. The move is either sent as plain text,
+// or as JSON with the notation in the field move, or with the fields from and to, or to and size.
+func readMove(r *http.Request) (string, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/plain" {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(strings.Fields(string(body)), " "), nil
+	}
+
+	var request moveRequest
+	if err := readJSON(r, &request); err != nil {
+		return "", err
+	}
+	switch {
+	case request.Move != "":
+		return strings.Join(strings.Fields(request.Move), " "), nil
+	case request.From != "" && request.To != "":
+		return request.From + " " + request.To, nil
+	case request.To != "" && request.Size != "":
+		return request.To + " " + request.Size, nil
+	default:
+		return "", errors.New("a move needs the field move, the fields from and to, or the fields to and size")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
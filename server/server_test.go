@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	s, err := New(Config{Engine: ai.EngineSpec{Name: "minimax"}, Limits: ai.Limits{MaxDepth: 3}})
+	assert.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// request sends the body as JSON, or as plain text if it is a string, and decodes the JSON response into result
+func request(t *testing.T, method, url string, body any, result any) int {
+	var reader *strings.Reader
+	contentType := "application/json"
+	switch body := body.(type) {
+	case nil:
+		reader = strings.NewReader("")
+	case string:
+		reader = strings.NewReader(body)
+		contentType = "text/plain"
+	default:
+		encoded, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = strings.NewReader(string(encoded))
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	response, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	if result != nil && response.StatusCode != http.StatusNoContent {
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(result))
+	}
+	return response.StatusCode
+}
+
+func createGame(t *testing.T, server *httptest.Server, body any) gameState {
+	var state gameState
+	assert.Equal(t, http.StatusCreated, request(t, http.MethodPost, server.URL+"/games", body, &state))
+	return state
+}
+
+func TestCreateGame(t *testing.T) {
+	server := newTestServer(t)
+
+	state := createGame(t, server, nil)
+	assert.NotEmpty(t, state.ID)
+	assert.Equal(t, "standard", state.Rules)
+	assert.Equal(t, "-,-,-/-,-,-/-,-,- 1 222/222", state.Position)
+	assert.Equal(t, 1, state.ActivePlayer)
+	assert.Equal(t, "none", state.Winner)
+	assert.Equal(t, []int{2, 2, 2}, state.RemainingPieces["player1"])
+	assert.Len(t, state.Board, 3)
+
+	var fetched gameState
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/games/"+state.ID, nil, &fetched))
+	assert.Equal(t, state, fetched)
+
+	state = createGame(t, server, createGameRequest{Rules: "gobblet"})
+	assert.Equal(t, "gobblet", state.Rules)
+	assert.Len(t, state.Board, 4)
+
+	state = createGame(t, server, createGameRequest{Position: "Sm,-,-/-,-,-/-,sL,- 2 121/112"})
+	assert.Equal(t, []pieceState{{Player: 1, Size: "S"}, {Player: 2, Size: "M"}}, state.Board[0][0])
+	assert.Equal(t, 2, state.ActivePlayer)
+
+	var response errorResponse
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/games", createGameRequest{Rules: "chess"}, &response))
+	assert.NotEmpty(t, response.Error)
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/games", createGameRequest{Position: "invalid"}, &response))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/games/unknown", nil, &response))
+}
+
+func TestMakeMove(t *testing.T) {
+	server := newTestServer(t)
+	state := createGame(t, server, nil)
+	movesURL := server.URL + "/games/" + state.ID + "/moves"
+
+	var legalMoves []string
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, movesURL, nil, &legalMoves))
+	assert.Len(t, legalMoves, 27)
+	assert.Contains(t, legalMoves, "b2 L")
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, movesURL, moveRequest{Move: "b2 L"}, &state))
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, movesURL, "a1 S", &state))
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, movesURL, moveRequest{From: "b2", To: "a1"}, &state))
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, movesURL, moveRequest{To: "b2", Size: "m"}, &state))
+	assert.Equal(t, []string{"b2 L", "a1 S", "b2 a1", "b2 M"}, state.Moves)
+	assert.Equal(t, "b2 M", state.LastMove)
+	assert.Equal(t, []pieceState{{Player: 2, Size: "S"}, {Player: 1, Size: "L"}}, state.Board[0][0])
+	assert.Equal(t, []int{2, 2, 1}, state.RemainingPieces["player1"])
+	assert.Equal(t, []int{1, 1, 2}, state.RemainingPieces["player2"])
+
+	var response errorResponse
+	assert.Equal(t, http.StatusUnprocessableEntity, request(t, http.MethodPost, movesURL, "b2 S", &response), "gobbling a larger piece is illegal")
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, movesURL, "z9 S", &response))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, movesURL, moveRequest{To: "c3"}, &response))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, movesURL, map[string]string{"piece": "S"}, &response))
+}
+
+func TestEngineMove(t *testing.T) {
+	server := newTestServer(t)
+	// Player 1 wins by placing a piece on c1
+	state := createGame(t, server, createGameRequest{Position: "L,M,-/s,m,-/-,-,- 1 211/112"})
+	url := server.URL + "/games/" + state.ID
+
+	var response engineMoveResponse
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, url+"/engine-move", engineMoveRequest{Depth: 1}, &response))
+	assert.Equal(t, "c1", response.Move[:2])
+	assert.Equal(t, ai.WinScore(game.Player1, 1), response.Evaluation)
+	assert.Equal(t, "player1", response.Game.Winner)
+	assert.Equal(t, []string{response.Move}, response.Game.Moves)
+
+	var errResponse errorResponse
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, url+"/engine-move", nil, &errResponse))
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, url+"/moves", "c3 S", &errResponse))
+	var legalMoves []string
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, url+"/moves", nil, &legalMoves))
+	assert.Empty(t, legalMoves)
+
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/games", createGameRequest{Rules: "size=9"}, &errResponse))
+	state = createGame(t, server, nil)
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/games/"+state.ID+"/engine-move",
+		engineMoveRequest{Time: "soon"}, &errResponse))
+}
+
+func TestEngineMoveWithoutLock(t *testing.T) {
+	s, err := New(Config{Engine: ai.EngineSpec{Name: "minimax"}, Limits: ai.Limits{MaxDepth: 20, MoveTime: time.Minute}})
+	assert.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	id := createGame(t, server, nil).ID
+	g, _ := s.games.get(id)
+
+	status := make(chan int)
+	go func() {
+		status <- request(t, http.MethodPost, server.URL+"/games/"+id+"/engine-move", nil, &errorResponse{})
+	}()
+	assert.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.thinking
+	}, 10*time.Second, time.Millisecond)
+
+	start := time.Now()
+	var state gameState
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, server.URL+"/games/"+id+"/moves", "b2 L", &state))
+	assert.Less(t, time.Since(start), 5*time.Second, "requests must not wait for the search")
+	assert.Equal(t, http.StatusConflict, <-status, "the move of the engine belongs to a position that changed")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	assert.Equal(t, []string{"b2 L"}, g.moves)
+}
+
+func TestDeleteGame(t *testing.T) {
+	server := newTestServer(t)
+	state := createGame(t, server, nil)
+
+	assert.Equal(t, http.StatusNoContent, request(t, http.MethodDelete, server.URL+"/games/"+state.ID, nil, nil))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodDelete, server.URL+"/games/"+state.ID, nil, &errorResponse{}))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/games/"+state.ID, nil, &errorResponse{}))
+}
+
+func TestLimits(t *testing.T) {
+	s, err := New(Config{
+		Engine:    ai.EngineSpec{Name: "minimax", Options: map[string]string{"depth": "5"}},
+		Limits:    ai.Limits{MaxDepth: 9},
+		MaxLimits: ai.Limits{MaxDepth: 7, MoveTime: 2 * time.Second},
+	})
+	assert.NoError(t, err)
+	g := &session{limits: ai.Limits{MaxDepth: 5}}
+
+	limits, err := s.limits(g, engineMoveRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, ai.Limits{MaxDepth: 5, MoveTime: 2 * time.Second}, limits, "searches must not take longer than the maximum time")
+
+	limits, err = s.limits(g, engineMoveRequest{Depth: 20, Time: "1s"})
+	assert.NoError(t, err)
+	assert.Equal(t, ai.Limits{MaxDepth: 7, MoveTime: time.Second}, limits)
+
+	s, err = New(Config{Engine: ai.EngineSpec{Name: "minimax"}})
+	assert.NoError(t, err)
+	limits, err = s.limits(g, engineMoveRequest{Time: "1h"})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxMoveTime, limits.MoveTime, "the time of engine moves must be bounded by default")
+
+	_, err = New(Config{Engine: ai.EngineSpec{Name: "unknown"}})
+	assert.Error(t, err)
+}
+
+func TestMaxGames(t *testing.T) {
+	s, err := New(Config{Engine: ai.EngineSpec{Name: "minimax"}, MaxGames: 2})
+	assert.NoError(t, err)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	first := createGame(t, server, nil)
+	createGame(t, server, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, request(t, http.MethodPost, server.URL+"/games", nil, nil),
+		"no more than MaxGames games must be held")
+
+	// Let the first game become idle
+	g, _ := s.games.get(first.ID)
+	g.lastUsed.Store(time.Now().Add(-2 * DefaultIdleTimeout).UnixNano())
+	createGame(t, server, nil)
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/games/"+first.ID, nil, nil),
+		"idle games must be removed to make room for new games")
+}
+
+func TestInvalidEngine(t *testing.T) {
+	_, err := New(Config{Engine: ai.EngineSpec{Name: "minimax", Options: map[string]string{"unknown": "1"}}})
+	assert.Error(t, err, "unknown engine options must be rejected")
+}
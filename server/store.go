@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// session is a game held by the server. Its mutex must be held while the board, the engine or the connections are used,
+// so requests for the same game are handled one after the other.
+type session struct {
+	mu           sync.Mutex
+	id           string
+	board        *game.Board
+	moves        []string // Played moves in the notation of game.ParseMove
+	engine       ai.Engine
+	limits       ai.Limits    // Limits of the engine, from the defaults of the server and the options of the engine spec
+	enginePlayer game.Player  // Player whose moves the engine makes without being asked, game.None if there is none
+	thinking     bool         // Whether a move of the engine player is being searched
+	cancelSearch func()       // Cancels the search of the engine player while thinking
+	lastUsed     atomic.Int64 // Time of the last request or live message of the game in Unix nanoseconds, see touch
+	live
+}
+
+// touch marks the game as used, so it is not removed as idle
+func (g *session) touch() {
+	g.lastUsed.Store(time.Now().UnixNano())
+}
+
+// errStoreFull is returned when a game is added to a store that holds its maximum number of games
+var errStoreFull = errors.New("too many games, try again later")
+
+// store holds the games of the server in memory. Games that were not used for idleTimeout are removed when a game is
+// added, and no more than maxGames games are held at the same time.
+type store struct {
+	mu          sync.Mutex
+	games       map[string]*session
+	maxGames    int
+	idleTimeout time.Duration
+}
+
+func newStore(maxGames int, idleTimeout time.Duration) *store {
+	return &store{games: make(map[string]*session), maxGames: maxGames, idleTimeout: idleTimeout}
+}
+
+// add stores the game under a new random ID. It first removes the idle games, which are returned so the caller can
+// close their connections, and returns errStoreFull if the store still holds maxGames games.
+func (s *store) add(g *session) (idle []*session, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := time.Now().Add(-s.idleTimeout).UnixNano()
+	for id, game := range s.games {
+		if game.lastUsed.Load() < deadline {
+			delete(s.games, id)
+			idle = append(idle, game)
+		}
+	}
+	if len(s.games) >= s.maxGames {
+		return idle, errStoreFull
+	}
+
+	g.id = newID()
+	for s.games[g.id] != nil {
+		g.id = newID()
+	}
+	g.touch()
+	s.games[g.id] = g
+	return idle, nil
+}
+
+// get returns the game with the ID and marks it as used
+func (s *store) get(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, found := s.games[id]
+	if found {
+		g.touch()
+	}
+	return g, found
+}
+
+func (s *store) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.games[id]
+	delete(s.games, id)
+	return found
+}
+
+func newID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
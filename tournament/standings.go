@@ -0,0 +1,137 @@
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// z95 is the quantile of the normal distribution for a 95% confidence interval
+const z95 = 1.959964
+
+// Score counts the wins, losses and draws of an engine
+type Score struct {
+	Wins, Losses, Draws int
+}
+
+// Games returns the number of games
+func (s Score) Games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// Points returns 1 point per win and half a point per draw
+func (s Score) Points() float64 {
+	return float64(s.Wins) + float64(s.Draws)/2
+}
+
+// Standing is the overall result of an engine in a tournament
+type Standing struct {
+	Engine string
+	Score
+	Elo      float64 // Elo difference to the average opponent, ±Inf if all games were won or lost
+	EloError float64 // Half the width of the 95% confidence interval of Elo
+}
+
+// Crosstable returns the score of every engine against every other engine, indexed like Result.Engines
+func (r *Result) Crosstable() [][]Score {
+	table := make([][]Score, len(r.Engines))
+	for i := range table {
+		table[i] = make([]Score, len(r.Engines))
+	}
+	for _, g := range r.Games {
+		switch g.Winner() {
+		case g.Player1:
+			table[g.Player1][g.Player2].Wins++
+			table[g.Player2][g.Player1].Losses++
+		case g.Player2:
+			table[g.Player2][g.Player1].Wins++
+			table[g.Player1][g.Player2].Losses++
+		default:
+			table[g.Player1][g.Player2].Draws++
+			table[g.Player2][g.Player1].Draws++
+		}
+	}
+	return table
+}
+
+// Standings returns the overall results of all engines, sorted by points
+func (r *Result) Standings() []Standing {
+	standings := make([]Standing, len(r.Engines))
+	for i, row := range r.Crosstable() {
+		standings[i].Engine = r.Engines[i]
+		for _, score := range row {
+			standings[i].Wins += score.Wins
+			standings[i].Losses += score.Losses
+			standings[i].Draws += score.Draws
+		}
+		standings[i].Elo, standings[i].EloError = eloEstimate(standings[i].Score)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Points() > standings[j].Points()
+	})
+	return standings
+}
+
+// EloDifference returns the Elo difference that corresponds to the expected score, a fraction between 0 and 1
+func EloDifference(expectedScore float64) float64 {
+	return -400 * math.Log10(1/expectedScore-1)
+}
+
+// eloEstimate returns the Elo difference of the score and the error of the 95% confidence interval,
+// estimated from the standard deviation of the results of the individual games
+func eloEstimate(score Score) (elo, eloError float64) {
+	games := float64(score.Games())
+	if games == 0 {
+		return 0, math.Inf(1)
+	}
+	mean := score.Points() / games
+	variance := (float64(score.Wins)*math.Pow(1-mean, 2) + float64(score.Draws)*math.Pow(0.5-mean, 2) +
+		float64(score.Losses)*math.Pow(mean, 2)) / games
+	margin := z95 * math.Sqrt(variance/games)
+
+	elo = EloDifference(mean)
+	low := EloDifference(math.Max(mean-margin, 0))
+	high := EloDifference(math.Min(mean+margin, 1))
+	return elo, (high - low) / 2
+}
+
+// WriteReport writes the standings and the crosstable of the tournament
+func (r *Result) WriteReport(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "Rank\tEngine\tGames\tWins\tLosses\tDraws\tScore\tElo\t")
+	for i, standing := range r.Standings() {
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%d\t%d\t%d\t%.1f%%\t%s\t\n", i+1, standing.Engine, standing.Games(),
+			standing.Wins, standing.Losses, standing.Draws, 100*standing.Points()/float64(max(standing.Games(), 1)),
+			formatElo(standing.Elo, standing.EloError))
+	}
+	fmt.Fprintln(writer)
+
+	// Crosstable with the wins, losses and draws of the engine of the row against the engine of the column
+	header := []string{""}
+	for i := range r.Engines {
+		header = append(header, fmt.Sprint(i+1))
+	}
+	fmt.Fprintln(writer, strings.Join(header, "\t")+"\t")
+	for i, row := range r.Crosstable() {
+		cells := []string{fmt.Sprintf("%d %s", i+1, r.Engines[i])}
+		for j, score := range row {
+			if i == j {
+				cells = append(cells, "-")
+			} else {
+				cells = append(cells, fmt.Sprintf("+%d -%d =%d", score.Wins, score.Losses, score.Draws))
+			}
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t")+"\t")
+	}
+	return writer.Flush()
+}
+
+func formatElo(elo, eloError float64) string {
+	if math.IsInf(elo, 0) || math.IsNaN(eloError) || math.IsInf(eloError, 0) {
+		return fmt.Sprintf("%+.0f", elo)
+	}
+	return fmt.Sprintf("%+.0f ± %.0f", elo, eloError)
+}
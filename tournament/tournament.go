@@ -0,0 +1,300 @@
+package tournament
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+)
+
+// Format determines which engines play against each other
+type Format int
+
+const (
+	RoundRobin Format = iota // Every engine plays against every other engine
+	Gauntlet                 // The first engine plays against all other engines
+)
+
+// DefaultMaxPlies draws games that are not decided, e.g. because both engines move pieces around without repeating positions
+const DefaultMaxPlies = 200
+
+// Config describes a tournament
+type Config struct {
+	Engines     []ai.EngineSpec
+	Format      Format
+	Rules       game.Rules // Rules of all games, game.DefaultRules if zero
+	Openings    []string   // Start positions in the format of game.Board.Encode, the start position of the rules if empty
+	Rounds      int        // Number of times every opening is played by every pairing with both colors
+	Limits      ai.Limits  // Default limits of the engines, overridden by the depth and time options of their specs
+	MaxPlies    int        // Games not decided after MaxPlies plies are drawn, in addition to threefold repetition
+	Concurrency int        // Number of games played in parallel, runtime.NumCPU() if 0
+}
+
+// GameResult is the outcome of a single game of a tournament
+type GameResult struct {
+	Player1, Player2 int // Index of the engines in Config.Engines
+	Record           *game.GameRecord
+}
+
+// Winner returns the index of the winning engine, -1 for a draw
+func (g GameResult) Winner() int {
+	switch g.Record.Result.Winner() {
+	case game.Player1:
+		return g.Player1
+	case game.Player2:
+		return g.Player2
+	default:
+		return -1
+	}
+}
+
+// Result holds all games of a finished tournament
+type Result struct {
+	Engines []string // Names of the engines, in the order of Config.Engines
+	Games   []GameResult
+}
+
+// pairing is a game that is still to be played
+type pairing struct {
+	player1, player2 int
+	opening          string
+}
+
+// Run plays all games of the tournament, with up to Concurrency games in parallel.
+// As engines must not be shared between goroutines, every goroutine creates its own engines when it first needs them
+// and reuses them for all its games, resetting engines that implement ai.GameResetter before every game.
+// progress is called after every finished game, it may be nil.
+func Run(ctx context.Context, config Config, progress func(GameResult)) (*Result, error) {
+	if len(config.Engines) < 2 {
+		return nil, errors.New("a tournament needs at least two engines")
+	}
+	// Validate every spec up front, so invalid specs are reported before any game is played
+	for _, spec := range config.Engines {
+		if err := ai.ValidateEngineSpec(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	pairings := schedule(config)
+	results := make([]GameResult, len(pairings))
+	errs := make([]error, len(pairings))
+	jobs := make(chan int)
+	var progressMutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for range orDefault(config.Concurrency, runtime.NumCPU()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := &worker{config: config}
+			defer w.close()
+			for i := range jobs {
+				results[i], errs[i] = w.playGame(ctx, pairings[i])
+				if errs[i] == nil && progress != nil {
+					progressMutex.Lock()
+					progress(results[i])
+					progressMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+schedule:
+	for i := range pairings {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Games: results}
+	for _, spec := range config.Engines {
+		result.Engines = append(result.Engines, spec.String())
+	}
+	return result, nil
+}
+
+// schedule returns all games of the tournament. Every pairing plays every opening with both colors in every round.
+func schedule(config Config) []pairing {
+	openings := config.Openings
+	if len(openings) == 0 {
+		openings = []string{game.NewBoardWithRules(config.rules()).Encode()}
+	}
+
+	var pairings []pairing
+	for round := 0; round < max(config.Rounds, 1); round++ {
+		for i := range config.Engines {
+			for j := i + 1; j < len(config.Engines); j++ {
+				if config.Format == Gauntlet && i > 0 {
+					continue
+				}
+				for _, opening := range openings {
+					pairings = append(pairings, pairing{i, j, opening}, pairing{j, i, opening})
+				}
+			}
+		}
+	}
+	return pairings
+}
+
+// worker holds the engines of a goroutine that plays games, indexed like Config.Engines
+type worker struct {
+	config  Config
+	engines []ai.Engine
+	limits  []ai.Limits
+}
+
+// engine returns the engine with the index, which is created when it is needed for the first time
+func (w *worker) engine(index int) (ai.Engine, ai.Limits, error) {
+	if w.engines == nil {
+		w.engines = make([]ai.Engine, len(w.config.Engines))
+		w.limits = make([]ai.Limits, len(w.config.Engines))
+	}
+	if w.engines[index] == nil {
+		engine, limits, err := ai.NewEngine(w.config.Engines[index], w.config.rules(), w.config.Limits)
+		if err != nil {
+			return nil, limits, err
+		}
+		w.engines[index], w.limits[index] = engine, limits
+	}
+	return w.engines[index], w.limits[index], nil
+}
+
+// close releases the resources of all engines that hold any, e.g. the processes of external engines
+func (w *worker) close() {
+	for _, engine := range w.engines {
+		_ = ai.CloseEngine(engine)
+	}
+}
+
+// playGame plays a single game until it is won or drawn
+func (w *worker) playGame(ctx context.Context, p pairing) (GameResult, error) {
+	config := w.config
+	board, err := game.ParsePositionWithRules(p.opening, config.rules())
+	if err != nil {
+		return GameResult{}, fmt.Errorf("opening %q: %w", p.opening, err)
+	}
+
+	players := [2]int{p.player1, p.player2}
+	engines := [2]ai.Engine{}
+	limits := [2]ai.Limits{}
+	for i, index := range players {
+		if engines[i], limits[i], err = w.engine(index); err != nil {
+			return GameResult{}, err
+		}
+		if resetter, ok := engines[i].(ai.GameResetter); ok {
+			resetter.NewGame()
+		}
+	}
+
+	record := game.NewGameRecord(board, config.Engines[p.player1].String(), config.Engines[p.player2].String())
+	board.DrawRules.MaxPlies = orDefault(config.MaxPlies, DefaultMaxPlies)
+	for !board.Outcome().IsOver() {
+		side := 0
+		if board.ActivePlayer == game.Player2 {
+			side = 1
+		}
+		result, err := engines[side].Search(ctx, board, limits[side])
+		if err != nil {
+			return GameResult{}, err
+		}
+		if err := board.MakeMove(result.BestMove); err != nil {
+			return GameResult{}, fmt.Errorf("%s played an invalid move: %w", config.Engines[players[side]], err)
+		}
+		record.AddMove(result.BestMove)
+	}
+	record.Finish(board.Outcome())
+	return GameResult{Player1: p.player1, Player2: p.player2, Record: record}, nil
+}
+
+// rules returns the rules of the tournament
+func (c Config) rules() game.Rules {
+	if c.Rules == (game.Rules{}) {
+		return game.DefaultRules
+	}
+	return c.Rules
+}
+
+// orDefault returns value, or fallback if value is not positive
+func orDefault(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+const (
+	// openingDepth is the depth of the search that rates the balance of openings
+	openingDepth = 4
+	// balancedMargin is the largest evaluation of a balanced opening for either player, less than a line with a
+	// single missing piece is worth to the heuristic evaluation
+	balancedMargin = 50
+)
+
+// Openings returns the balanced positions among all distinct positions after the given number of plies from the
+// start position of the rules. A position is balanced if a shallow search evaluates it close to 0, so neither player
+// has an obvious advantage. Symmetric positions are only included once.
+func Openings(rules game.Rules, plies int) []string {
+	seen := make(map[uint64]bool)
+	positions := []string{game.NewBoardWithRules(rules).Encode()}
+	for ply := 0; ply < plies; ply++ {
+		var next []string
+		for _, position := range positions {
+			board, _ := game.ParsePositionWithRules(position, rules)
+			for _, move := range board.GetPossibleMoves() {
+				board.MustMakeMove(move)
+				if key, _ := board.CanonicalHash(); !seen[key] && !board.Outcome().IsOver() {
+					seen[key] = true
+					next = append(next, board.Encode())
+				}
+				board.MustUndoMove(move)
+			}
+		}
+		positions = next
+	}
+
+	search := ai.NewMinimax(ai.WithTranspositionTableSize(1))
+	balanced := positions[:0]
+	for _, position := range positions {
+		board, _ := game.ParsePositionWithRules(position, rules)
+		result, _ := search.Search(context.Background(), board, ai.Limits{MaxDepth: openingDepth})
+		if result.Evaluation >= -balancedMargin && result.Evaluation <= balancedMargin {
+			balanced = append(balanced, position)
+		}
+	}
+	return balanced
+}
+
+// ReadOpenings reads one encoded position of the rules per line. Empty lines and lines starting with # are skipped.
+func ReadOpenings(r io.Reader, rules game.Rules) ([]string, error) {
+	var openings []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		position := strings.TrimSpace(scanner.Text())
+		if position == "" || strings.HasPrefix(position, "#") {
+			continue
+		}
+		if _, err := game.ParsePositionWithRules(position, rules); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		openings = append(openings, position)
+	}
+	return openings, scanner.Err()
+}
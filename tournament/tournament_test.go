@@ -0,0 +1,142 @@
+package tournament
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gibhub.com/bef1993/gobblet-gobblers/ai"
+	"gibhub.com/bef1993/gobblet-gobblers/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func specs(t *testing.T, engines ...string) []ai.EngineSpec {
+	var parsed []ai.EngineSpec
+	for _, engine := range engines {
+		spec, err := ai.ParseEngineSpec(engine)
+		assert.NoError(t, err)
+		parsed = append(parsed, spec)
+	}
+	return parsed
+}
+
+func TestSchedule(t *testing.T) {
+	config := Config{Engines: specs(t, "random", "minimax", "mcts"), Openings: []string{game.StartPosition, game.StartPosition}, Rounds: 2}
+	assert.Len(t, schedule(config), 3*2*2*2, "every pairing must play every opening with both colors in every round")
+
+	config.Format = Gauntlet
+	pairings := schedule(config)
+	assert.Len(t, pairings, 2*2*2*2)
+	for _, p := range pairings {
+		assert.True(t, p.player1 == 0 || p.player2 == 0, "every game of a gauntlet must include the first engine")
+	}
+}
+
+func TestEloEstimate(t *testing.T) {
+	assert.InDelta(t, 0, EloDifference(0.5), 1e-9)
+	assert.InDelta(t, 190.85, EloDifference(0.75), 0.01)
+	assert.InDelta(t, -190.85, EloDifference(0.25), 0.01)
+
+	elo, eloError := eloEstimate(Score{Wins: 60, Losses: 30, Draws: 10})
+	assert.InDelta(t, EloDifference(0.65), elo, 1e-9)
+	assert.Greater(t, eloError, 0.0)
+	_, moreGamesError := eloEstimate(Score{Wins: 600, Losses: 300, Draws: 100})
+	assert.Less(t, moreGamesError, eloError, "error must shrink with more games")
+
+	elo, _ = eloEstimate(Score{Wins: 10})
+	assert.True(t, math.IsInf(elo, 1), "only wins must result in infinite Elo")
+}
+
+func TestRun(t *testing.T) {
+	config := Config{
+		Engines:     specs(t, "random:seed=1", "minimax:depth=3"),
+		Openings:    Openings(game.DefaultRules, 1),
+		Limits:      ai.Limits{MaxDepth: 9},
+		Concurrency: 4,
+	}
+	var played int
+	result, err := Run(context.Background(), config, func(GameResult) { played++ })
+	assert.NoError(t, err)
+	assert.Len(t, result.Games, 2*len(config.Openings))
+	assert.Equal(t, len(result.Games), played, "progress must be reported for every game")
+
+	for _, g := range result.Games {
+		assert.True(t, g.Record.Result.IsOver(), "every game must be won or drawn")
+		_, err := g.Record.Replay()
+		assert.NoError(t, err, "records must contain valid games")
+	}
+
+	standings := result.Standings()
+	assert.Equal(t, "minimax:depth=3", standings[0].Engine, "minimax must beat random moves")
+	assert.Equal(t, len(result.Games), standings[0].Games())
+
+	var report bytes.Buffer
+	assert.NoError(t, result.WriteReport(&report))
+	assert.Contains(t, report.String(), "random:seed=1")
+}
+
+func TestRunInvalidEngine(t *testing.T) {
+	_, err := Run(context.Background(), Config{Engines: specs(t, "random", "unknown")}, nil)
+	assert.Error(t, err)
+}
+
+func TestOpenings(t *testing.T) {
+	// The first piece can be placed in a corner, on an edge or in the center in three sizes, but a large piece
+	// or a piece in the center gives Player 1 a clear advantage
+	openings := Openings(game.DefaultRules, 1)
+	assert.Len(t, openings, 4)
+	search := ai.NewMinimax(ai.WithTranspositionTableSize(1))
+	for _, opening := range openings {
+		board, err := game.ParsePosition(opening)
+		assert.NoError(t, err)
+		assert.Equal(t, game.Player2, board.ActivePlayer)
+
+		result, err := search.Search(context.Background(), board, ai.Limits{MaxDepth: openingDepth})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, max(result.Evaluation, -result.Evaluation), balancedMargin, "openings must be balanced")
+	}
+}
+
+// countingEngine plays random moves and counts how often it is created and reset
+type countingEngine struct {
+	ai.Engine
+	resets *atomic.Int32
+}
+
+func (c countingEngine) NewGame() {
+	c.resets.Add(1)
+}
+
+var countingCreations, countingResets atomic.Int32
+
+func init() {
+	ai.RegisterEngine("counting", func(options *ai.EngineOptions) (ai.Engine, error) {
+		if options.Validating() {
+			return nil, nil
+		}
+		countingCreations.Add(1)
+		return countingEngine{Engine: ai.NewRandomEngine(rand.New(rand.NewSource(1))), resets: &countingResets}, nil
+	})
+}
+
+func TestRunReusesEngines(t *testing.T) {
+	config := Config{Engines: specs(t, "counting", "random:seed=1"), Rounds: 3, Concurrency: 1}
+	result, err := Run(context.Background(), config, nil)
+	assert.NoError(t, err)
+	assert.Len(t, result.Games, 6)
+	assert.Equal(t, int32(1), countingCreations.Load(), "a worker must create an engine only once")
+	assert.Equal(t, int32(6), countingResets.Load(), "engines must be reset before every game")
+}
+
+func TestReadOpenings(t *testing.T) {
+	openings, err := ReadOpenings(strings.NewReader("# balanced openings\n\n"+game.StartPosition+"\n"), game.DefaultRules)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{game.StartPosition}, openings)
+
+	_, err = ReadOpenings(strings.NewReader("-,-,-/-,-,- 1 222/222\n"), game.DefaultRules)
+	assert.Error(t, err)
+}